@@ -29,5 +29,10 @@ func main() {
 
 		&persistence.SC2ReplayStatsUser{},
 		&persistence.Subscription{},
+
+		&persistence.CritterKill{},
+		&persistence.UnknownCatalogName{},
+		&persistence.Benchmark{},
+		&persistence.BenchmarkStep{},
 	)
 }
@@ -4,6 +4,8 @@ import (
 	"github.com/dragaera/probius/internal/config"
 	"github.com/dragaera/probius/internal/discord"
 	"github.com/dragaera/probius/internal/persistence"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"github.com/dragaera/probius/internal/sc2replay/units"
 	"github.com/joho/godotenv"
 	"log"
 )
@@ -17,6 +19,18 @@ func main() {
 	// Will `log.Fatal()` if an env variable is missing
 	cfg := config.ConfigFromEnv()
 
+	sc2replay.DiagnosticsLogLevel, err = sc2replay.ParseSeverity(cfg.ReplayAnalysis.DiagnosticsLogLevel)
+	if err != nil {
+		log.Fatal("Invalid diagnostics log level: ", err)
+	}
+
+	if len(cfg.UnitCatalog.OverridesPath) > 0 {
+		err = units.LoadCatalogOverrides(cfg.UnitCatalog.OverridesPath)
+		if err != nil {
+			log.Fatal("Error while loading unit catalog overrides: ", err)
+		}
+	}
+
 	bot, err := discord.Create(&discord.Bot{
 		Config: cfg,
 	})
@@ -29,9 +43,15 @@ func main() {
 		log.Fatal("Error while initializing ORM persistence layer: ", err)
 	}
 
+	// TODO: Error here is always nil, see workers.
+	redis, _ := persistence.InitializeRedis(
+		cfg.Redis.Host,
+		cfg.Redis.Port,
+	)
+
 	log.Print("Starting Discord bot.")
 
-	err = bot.Run(orm)
+	err = bot.Run(orm, redis)
 	if err != nil {
 		log.Fatal("Error while starting Discord bot: ", err)
 	}
@@ -1,6 +1,6 @@
 module github.com/dragaera/probius
 
-go 1.14
+go 1.16
 
 require (
 	github.com/bwmarrin/discordgo v0.22.0
@@ -14,6 +14,7 @@ require (
 	github.com/robfig/cron v1.2.0 // indirect
 	github.com/throttled/throttled/v2 v2.6.0
 	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a // indirect
+	golang.org/x/image v0.0.0-20200927104501-e162460cd6b5
 	golang.org/x/sys v0.0.0-20200828150025-8dfe04af21d5 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 	gorm.io/driver/postgres v1.0.0
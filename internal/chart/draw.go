@@ -0,0 +1,99 @@
+package chart
+
+import (
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+var (
+	// Matches Discord's dark theme, so the charts blend in
+	backgroundColor = color.RGBA{0x2f, 0x31, 0x36, 0xff}
+	foregroundColor = color.RGBA{0xdc, 0xdd, 0xde, 0xff}
+	gridColor       = color.RGBA{0x4f, 0x54, 0x5c, 0xff}
+)
+
+// Colors used for series which don't specify their own one. Roughly follows
+// the default player colors of SC2.
+var Palette = []color.RGBA{
+	color.RGBA{0xb4, 0x14, 0x1e, 0xff}, // Red
+	color.RGBA{0x00, 0x42, 0xff, 0xff}, // Blue
+	color.RGBA{0x1c, 0xa7, 0xea, 0xff}, // Teal
+	color.RGBA{0x54, 0x00, 0x81, 0xff}, // Purple
+	color.RGBA{0xeb, 0xe1, 0x29, 0xff}, // Yellow
+	color.RGBA{0xfe, 0x8a, 0x0e, 0xff}, // Orange
+	color.RGBA{0x16, 0x80, 0x00, 0xff}, // Green
+	color.RGBA{0xcc, 0xa6, 0xfc, 0xff}, // Pink
+}
+
+var face = basicfont.Face7x13
+
+func newCanvas(width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{backgroundColor}, image.ZP, draw.Src)
+
+	return img
+}
+
+// Draw text with its baseline starting at the given point.
+func drawText(img draw.Image, x int, y int, text string, col color.Color) {
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(col),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	drawer.DrawString(text)
+}
+
+// Width of the given text in pixels.
+func textWidth(text string) int {
+	drawer := font.Drawer{Face: face}
+	return drawer.MeasureString(text).Round()
+}
+
+// Draw a line of the given thickness using Bresenham's algorithm.
+func drawLine(img draw.Image, x0 int, y0 int, x1 int, y1 int, thickness int, col color.Color) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx := 1
+	if x0 > x1 {
+		sx = -1
+	}
+	sy := 1
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+
+	for {
+		fillRect(img, x0-thickness/2, y0-thickness/2, thickness, thickness, col)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func fillRect(img draw.Image, x int, y int, width int, height int, col color.Color) {
+	draw.Draw(img, image.Rect(x, y, x+width, y+height), &image.Uniform{col}, image.ZP, draw.Over)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,109 @@
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+type Heatmap struct {
+	Title string
+
+	// Dimensions of the grid of values
+	Width  int
+	Height int
+	// Values row by row, starting at the bottom left.
+	Values []int
+
+	// Size of each grid cell in pixels
+	Scale int
+}
+
+// Radius in cells across which each value is spread out, to make sparse
+// data readable.
+const heatmapBlurRadius = 2
+
+const heatmapTitleHeight = 20
+
+// Render the heatmap as PNG.
+func (heatmap *Heatmap) Render(w io.Writer) error {
+	if heatmap.Width <= 0 || heatmap.Height <= 0 || heatmap.Scale <= 0 {
+		return fmt.Errorf("Heatmap dimensions of %dx%d are invalid", heatmap.Width, heatmap.Height)
+	}
+	if len(heatmap.Values) != heatmap.Width*heatmap.Height {
+		return fmt.Errorf("Heatmap has %d values, expected %d", len(heatmap.Values), heatmap.Width*heatmap.Height)
+	}
+
+	img := newCanvas(heatmap.Width*heatmap.Scale, heatmap.Height*heatmap.Scale+heatmapTitleHeight)
+	drawText(img, 5, 15, heatmap.Title, foregroundColor)
+
+	blurred, max := heatmap.blur()
+	if max == 0 {
+		return png.Encode(w, img)
+	}
+
+	for y := 0; y < heatmap.Height; y++ {
+		for x := 0; x < heatmap.Width; x++ {
+			value := blurred[y*heatmap.Width+x]
+			if value == 0 {
+				continue
+			}
+
+			// Logarithmic, as a few positions (eg rally points)
+			// tend to dominate.
+			intensity := math.Log1p(value) / math.Log1p(max)
+
+			// Image coordinates start at the top left
+			top := (heatmap.Height-1-y)*heatmap.Scale + heatmapTitleHeight
+			fillRect(img, x*heatmap.Scale, top, heatmap.Scale, heatmap.Scale, heatColor(intensity))
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// Spread out values to neighbouring cells. Returns the blurred values, and
+// their maximum.
+func (heatmap *Heatmap) blur() ([]float64, float64) {
+	blurred := make([]float64, len(heatmap.Values))
+	max := 0.0
+
+	for y := 0; y < heatmap.Height; y++ {
+		for x := 0; x < heatmap.Width; x++ {
+			value := heatmap.Values[y*heatmap.Width+x]
+			if value == 0 {
+				continue
+			}
+
+			for dy := -heatmapBlurRadius; dy <= heatmapBlurRadius; dy++ {
+				for dx := -heatmapBlurRadius; dx <= heatmapBlurRadius; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= heatmap.Width || ny >= heatmap.Height {
+						continue
+					}
+
+					weight := 1 / (1 + math.Hypot(float64(dx), float64(dy)))
+					i := ny*heatmap.Width + nx
+					blurred[i] += float64(value) * weight
+					max = math.Max(max, blurred[i])
+				}
+			}
+		}
+	}
+
+	return blurred, max
+}
+
+// Color ranging from dark red (0) via red and orange to yellow (1).
+func heatColor(intensity float64) color.RGBA {
+	intensity = math.Max(0, math.Min(1, intensity))
+
+	return color.RGBA{
+		R: uint8(96 + 159*math.Min(1, intensity*2)),
+		G: uint8(255 * math.Max(0, intensity*2-1)),
+		B: 0,
+		A: 0xff,
+	}
+}
@@ -0,0 +1,168 @@
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+type Point struct {
+	X float64
+	Y float64
+}
+
+type Series struct {
+	Name string
+	// Leave empty to use a color of the palette
+	Color  color.RGBA
+	Points []Point
+}
+
+type LineChart struct {
+	Title  string
+	Width  int
+	Height int
+	Series []Series
+
+	// Formatters for axis labels. Default to printing the plain number.
+	FormatX func(float64) string
+	FormatY func(float64) string
+}
+
+const (
+	marginTop    = 40
+	marginBottom = 30
+	marginLeft   = 60
+	marginRight  = 20
+
+	tickCount = 5
+)
+
+// Render the chart as PNG.
+func (chart *LineChart) Render(w io.Writer) error {
+	if chart.Width <= marginLeft+marginRight || chart.Height <= marginTop+marginBottom {
+		return fmt.Errorf("Chart dimensions of %dx%d are too small", chart.Width, chart.Height)
+	}
+
+	formatX := chart.FormatX
+	if formatX == nil {
+		formatX = formatNumber
+	}
+	formatY := chart.FormatY
+	if formatY == nil {
+		formatY = formatNumber
+	}
+
+	img := newCanvas(chart.Width, chart.Height)
+
+	// Plot area
+	left := marginLeft
+	right := chart.Width - marginRight
+	top := marginTop
+	bottom := chart.Height - marginBottom
+
+	minX, maxX, maxY := chart.bounds()
+	maxY = niceCeil(maxY)
+
+	toPixel := func(p Point) (int, int) {
+		x := left + int(math.Round((p.X-minX)/(maxX-minX)*float64(right-left)))
+		y := bottom - int(math.Round(p.Y/maxY*float64(bottom-top)))
+		return x, y
+	}
+
+	// Grid and axis labels
+	for i := 0; i <= tickCount; i++ {
+		yValue := maxY * float64(i) / tickCount
+		_, y := toPixel(Point{X: minX, Y: yValue})
+		drawLine(img, left, y, right, y, 1, gridColor)
+		label := formatY(yValue)
+		drawText(img, left-textWidth(label)-5, y+4, label, foregroundColor)
+
+		xValue := minX + (maxX-minX)*float64(i)/tickCount
+		x, _ := toPixel(Point{X: xValue, Y: 0})
+		drawLine(img, x, bottom, x, bottom+4, 1, foregroundColor)
+		label = formatX(xValue)
+		drawText(img, x-textWidth(label)/2, bottom+18, label, foregroundColor)
+	}
+	drawLine(img, left, top, left, bottom, 1, foregroundColor)
+	drawLine(img, left, bottom, right, bottom, 1, foregroundColor)
+
+	// Title and legend
+	drawText(img, left, 18, chart.Title, foregroundColor)
+	legendX := left
+	for i, series := range chart.Series {
+		col := chart.seriesColor(i)
+		fillRect(img, legendX, 25, 10, 10, col)
+		drawText(img, legendX+14, 35, series.Name, foregroundColor)
+		legendX += textWidth(series.Name) + 30
+	}
+
+	// Data
+	for i, series := range chart.Series {
+		col := chart.seriesColor(i)
+		for j := 1; j < len(series.Points); j++ {
+			x0, y0 := toPixel(series.Points[j-1])
+			x1, y1 := toPixel(series.Points[j])
+			drawLine(img, x0, y0, x1, y1, 2, col)
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// Return the smallest and largest X value, and the largest Y value. The Y
+// axis always starts at zero.
+func (chart *LineChart) bounds() (float64, float64, float64) {
+	minX := math.Inf(1)
+	maxX := math.Inf(-1)
+	maxY := 0.0
+
+	for _, series := range chart.Series {
+		for _, p := range series.Points {
+			minX = math.Min(minX, p.X)
+			maxX = math.Max(maxX, p.X)
+			maxY = math.Max(maxY, p.Y)
+		}
+	}
+
+	// Prevent division by zero for empty or flat charts
+	if math.IsInf(minX, 0) {
+		minX, maxX = 0, 1
+	}
+	if maxX <= minX {
+		maxX = minX + 1
+	}
+	if maxY <= 0 {
+		maxY = 1
+	}
+
+	return minX, maxX, maxY
+}
+
+func (chart *LineChart) seriesColor(i int) color.RGBA {
+	col := chart.Series[i].Color
+	if col.A == 0 {
+		col = Palette[i%len(Palette)]
+	}
+
+	return col
+}
+
+// Round up to a number which divides nicely into ticks, eg 1, 2 or 5 times a
+// power of ten.
+func niceCeil(x float64) float64 {
+	magnitude := math.Pow(10, math.Floor(math.Log10(x)))
+	for _, step := range []float64{1, 2, 5, 10} {
+		if step*magnitude >= x {
+			return step * magnitude
+		}
+	}
+
+	return 10 * magnitude
+}
+
+func formatNumber(x float64) string {
+	return fmt.Sprintf("%.0f", x)
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -13,11 +14,16 @@ type Config struct {
 	Redis          RedisConfig
 	Worker         WorkerConfig
 	SC2ReplayStats SC2ReplayStatsConfig
+	ReplayCache    ReplayCacheConfig
+	UnitCatalog    UnitCatalogConfig
+	ReplayAnalysis ReplayAnalysisConfig
 }
 
 type DiscordConfig struct {
 	ClientID string
 	Token    string
+	// Discord user IDs allowed to use admin commands
+	AdminIDs []string
 }
 
 type DBConfig struct {
@@ -50,6 +56,26 @@ type SC2ReplayStatsConfig struct {
 	RateLimitBurst   int
 }
 
+type ReplayCacheConfig struct {
+	// In seconds
+	TTL int
+	// In bytes. Results larger than this are not cached.
+	MaxEntrySize int
+	// In bytes. Results of replays larger than this are not cached.
+	MaxReplaySize int
+}
+
+type UnitCatalogConfig struct {
+	// Path to JSON file overriding or extending the embedded catalogs.
+	// Empty if none.
+	OverridesPath string
+}
+
+type ReplayAnalysisConfig struct {
+	// Minimum severity of diagnostics to log: info, warning or error
+	DiagnosticsLogLevel string
+}
+
 func (cfg *DBConfig) DBURL() string {
 	return fmt.Sprintf(
 		"host=%v port=%v user=%v dbname=%v password=%v sslmode=%v",
@@ -68,6 +94,7 @@ func ConfigFromEnv() Config {
 	discordCfg := DiscordConfig{}
 	discordCfg.ClientID = fromEnv("DISCORD_CLIENT_ID")
 	discordCfg.Token = fromEnv("DISCORD_TOKEN")
+	discordCfg.AdminIDs = listFromEnvWithDefault("DISCORD_ADMIN_IDS", []string{})
 
 	dbCfg := DBConfig{}
 	dbCfg.User = fromEnv("DB_USER")
@@ -92,12 +119,26 @@ func ConfigFromEnv() Config {
 	sc2rCfg.RateLimitAverage = intFromEnvWithDefault("SC2_REPLAY_STATS_RATE_LIMIT_AVERAGE", 1)
 	sc2rCfg.RateLimitBurst = intFromEnvWithDefault("SC2_REPLAY_STATS_RATE_LIMIT_BURST", 2)
 
+	replayCacheCfg := ReplayCacheConfig{}
+	replayCacheCfg.TTL = intFromEnvWithDefault("REPLAY_CACHE_TTL", 24*60*60)
+	replayCacheCfg.MaxEntrySize = intFromEnvWithDefault("REPLAY_CACHE_MAX_ENTRY_SIZE", 256*1024)
+	replayCacheCfg.MaxReplaySize = intFromEnvWithDefault("REPLAY_CACHE_MAX_REPLAY_SIZE", 10*1024*1024)
+
+	unitCatalogCfg := UnitCatalogConfig{}
+	unitCatalogCfg.OverridesPath = fromEnvWithDefault("UNIT_CATALOG_OVERRIDES", "")
+
+	replayAnalysisCfg := ReplayAnalysisConfig{}
+	replayAnalysisCfg.DiagnosticsLogLevel = fromEnvWithDefault("REPLAY_DIAGNOSTICS_LOG_LEVEL", "warning")
+
 	cfg := Config{
 		DB:             dbCfg,
 		Discord:        discordCfg,
 		Redis:          redisCfg,
 		Worker:         workerCfg,
 		SC2ReplayStats: sc2rCfg,
+		ReplayCache:    replayCacheCfg,
+		UnitCatalog:    unitCatalogCfg,
+		ReplayAnalysis: replayAnalysisCfg,
 	}
 
 	return cfg
@@ -144,6 +185,21 @@ func intFromEnvWithDefault(key string, fallback int) int {
 	return val
 }
 
+// Comma-separated list, with surrounding whitespace and empty elements
+// removed.
+func listFromEnvWithDefault(key string, fallback []string) []string {
+	str := fromEnvWithDefault(key, strings.Join(fallback, ","))
+
+	list := make([]string, 0)
+	for _, elem := range strings.Split(str, ",") {
+		if elem = strings.TrimSpace(elem); len(elem) > 0 {
+			list = append(list, elem)
+		}
+	}
+
+	return list
+}
+
 func boolFromEnv(key string) bool {
 	str := fromEnv(key)
 
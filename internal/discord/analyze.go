@@ -0,0 +1,121 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"strings"
+	"time"
+)
+
+func (bot *Bot) cmdAnalyze(ctxt CommandContext) bool {
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		summary, err := replay.Summary()
+		if err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		embed := buildSummaryEmbed(&summary)
+		ctxt.RespondEmbed(&embed)
+	})
+
+	return true
+}
+
+func buildSummaryEmbed(summary *sc2replay.Summary) discordgo.MessageEmbed {
+	winner := "Unknown"
+	if winners := summary.Winners(); len(winners) > 0 {
+		winner = strings.Join(winners, ", ")
+	}
+
+	players := strings.Builder{}
+	for _, player := range summary.Players {
+		mmr := "unknown"
+		if player.MMR > 0 {
+			mmr = fmt.Sprintf("%d", player.MMR)
+		}
+		players.WriteString(fmt.Sprintf("%v (%v): %v MMR\n", player.Name, player.Race, mmr))
+	}
+	if players.Len() == 0 {
+		players.WriteString("None")
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		&discordgo.MessageEmbedField{
+			Name:   "Map",
+			Value:  summary.Map,
+			Inline: true,
+		},
+		&discordgo.MessageEmbedField{
+			Name:   "Winner",
+			Value:  fmt.Sprintf("||%v||", winner),
+			Inline: true,
+		},
+		&discordgo.MessageEmbedField{
+			Name:   "Game Length",
+			Value:  formatTimestamp(summary.Duration),
+			Inline: false,
+		},
+		&discordgo.MessageEmbedField{
+			Name:   "Matchup",
+			Value:  summary.Matchup,
+			Inline: true,
+		},
+		&discordgo.MessageEmbedField{
+			Name:   "Region",
+			Value:  summary.Region,
+			Inline: true,
+		},
+		&discordgo.MessageEmbedField{
+			Name:   "Version",
+			Value:  summary.Version,
+			Inline: true,
+		},
+		&discordgo.MessageEmbedField{
+			Name:   "Players",
+			Value:  truncate(players.String(), embedFieldLimit),
+			Inline: false,
+		},
+	}
+
+	embed := discordgo.MessageEmbed{
+		Title:     constructSummaryTitle(summary),
+		Timestamp: summary.Time.Format(time.RFC3339),
+		Thumbnail: &discordgo.MessageEmbedThumbnail{
+			URL: mapThumbnailURL(summary.Map),
+		},
+		Fields: fields,
+	}
+
+	return embed
+}
+
+// Equivalent of `constructReplayTitle`, for replays which are not known to
+// SC2Replaystats.
+func constructSummaryTitle(summary *sc2replay.Summary) string {
+	teams := make(map[int64][]string)
+	teamIDs := make([]int64, 0)
+
+	for _, player := range summary.Players {
+		if _, ok := teams[player.TeamID]; !ok {
+			teamIDs = append(teamIDs, player.TeamID)
+		}
+
+		shorthand := "?"
+		if len(player.Race) > 0 {
+			shorthand = player.Race[:1]
+		}
+		teams[player.TeamID] = append(
+			teams[player.TeamID],
+			fmt.Sprintf("[%v] %v", shorthand, player.Name),
+		)
+	}
+
+	teamMonikers := make([]string, len(teamIDs))
+	for i, teamID := range teamIDs {
+		teamMonikers[i] = strings.Join(teams[teamID], ", ")
+	}
+
+	return strings.Join(teamMonikers, " vs ")
+}
@@ -0,0 +1,88 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"strings"
+)
+
+func (bot *Bot) cmdAPM(ctxt CommandContext) bool {
+	selector := "all"
+	if len(ctxt.Args()) > 0 {
+		selector = ctxt.Args()[0]
+	}
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		playerIDs, err := selectPlayers(replay, selector)
+		if err != nil {
+			ctxt.Respond(err.Error())
+			return
+		}
+
+		apm := sc2replay.APM{Replay: replay}
+		if err := apm.Calculate(-1); err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		fields := make([]*discordgo.MessageEmbedField, 0, len(playerIDs))
+		for _, playerID := range playerIDs {
+			name, err := replay.PlayerName(playerID)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:   name,
+				Value:  truncate(buildAPMList(apm.Players[playerID]), embedFieldLimit),
+				Inline: true,
+			})
+		}
+
+		embed := discordgo.MessageEmbed{
+			Title:  "APM report",
+			Fields: fields,
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: "APM excludes camera movements. EPM only counts commands and control group assignments.",
+			},
+		}
+		ctxt.RespondEmbed(&embed)
+	})
+
+	return true
+}
+
+func buildAPMList(actions *sc2replay.PlayerActions) string {
+	out := strings.Builder{}
+
+	if actions == nil {
+		return "No data available"
+	}
+
+	fmt.Fprintf(&out, "**APM**: %.0f\n", actions.APM())
+	fmt.Fprintf(&out, "**EPM**: %.0f\n", actions.EPM())
+	fmt.Fprintf(
+		&out,
+		"%d commands, %d selections, %d control groups\n",
+		actions.Total.Commands,
+		actions.Total.Selections,
+		actions.Total.ControlGroups,
+	)
+
+	if len(actions.PerMinute) > 0 {
+		out.WriteString("**Per minute** (APM/EPM)\n")
+	}
+	for minute, counts := range actions.PerMinute {
+		fmt.Fprintf(
+			&out,
+			"`%02d` %d/%d\n",
+			minute+1,
+			counts.Actions(),
+			counts.EffectiveActions(),
+		)
+	}
+
+	return out.String()
+}
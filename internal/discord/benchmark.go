@@ -0,0 +1,377 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/persistence"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"github.com/dragaera/probius/internal/sc2replay/units"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const benchmarkMaxSteps int = 100
+
+// Subcommands of `!bench`, which are thus not allowed as benchmark names.
+var benchmarkSubcommands = map[string]bool{
+	"list":   true,
+	"show":   true,
+	"set":    true,
+	"delete": true,
+}
+
+func (bot *Bot) cmdBench(ctxt CommandContext) bool {
+	// Steps of a benchmark are passed on the lines following the command,
+	// so arguments are taken from the first line only.
+	lines := strings.Split(ctxt.Msg().Content, "\n")
+	args, options := splitOptions(strings.Fields(lines[0])[1:])
+	if len(args) < 1 {
+		return false
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		return bot.cmdBenchList(ctxt)
+	case "show":
+		if len(args) != 2 {
+			return false
+		}
+		return bot.cmdBenchShow(ctxt, args[1])
+	case "set":
+		if len(args) != 2 {
+			return false
+		}
+		return bot.cmdBenchSet(ctxt, args[1], options["guild"], lines[1:])
+	case "delete":
+		if len(args) != 2 {
+			return false
+		}
+		return bot.cmdBenchDelete(ctxt, args[1], options["guild"])
+	default:
+		if len(args) > 2 {
+			return false
+		}
+		selector := ""
+		if len(args) > 1 {
+			selector = args[1]
+		}
+		return bot.cmdBenchCompare(ctxt, args[0], selector)
+	}
+}
+
+func (bot *Bot) cmdBenchCompare(ctxt CommandContext, name string, selector string) bool {
+	benchmark, ok, err := persistence.FindBenchmark(bot.orm, name, ctxt.User().ID, ctxt.Guild().ID)
+	if err != nil {
+		ctxt.InternalError(err)
+		return true
+	}
+	if !ok {
+		ctxt.Respond(fmt.Sprintf("No benchmark named '%v' found. Use `!bench list` to see the available ones.", name))
+		return true
+	}
+
+	steps := make([]sc2replay.BenchmarkStep, 0, len(benchmark.Steps))
+	for _, step := range benchmark.Steps {
+		steps = append(steps, sc2replay.BenchmarkStep{
+			Name:    step.Name,
+			Supply:  step.Supply,
+			Seconds: step.Seconds,
+		})
+	}
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		playerIDs, err := selectPlayers(replay, selector)
+		if err != nil {
+			ctxt.Respond(err.Error())
+			return
+		}
+
+		for _, playerID := range playerIDs {
+			bench := sc2replay.Benchmark{
+				Replay:   replay,
+				PlayerID: playerID,
+				Steps:    steps,
+			}
+			if err := bench.Generate(); err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			embed, err := buildBenchmarkEmbed(benchmark.Name, &bench)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+			ctxt.RespondEmbed(&embed)
+		}
+	})
+
+	return true
+}
+
+func buildBenchmarkEmbed(name string, bench *sc2replay.Benchmark) (discordgo.MessageEmbed, error) {
+	out := strings.Builder{}
+	truncated := 0
+
+	for i, result := range bench.Results {
+		line := fmt.Sprintf(
+			"`%v` %d %v: ",
+			formatTimestamp(time.Duration(result.Step.Seconds)*time.Second),
+			result.Step.Supply,
+			result.Step.Name,
+		)
+
+		if result.Done {
+			started, err := bench.Replay.DurationAt(result.StartLoop)
+			if err != nil {
+				return discordgo.MessageEmbed{}, err
+			}
+			delay, err := formatDelay(bench.Replay, result.Delay())
+			if err != nil {
+				return discordgo.MessageEmbed{}, err
+			}
+			line += fmt.Sprintf("`%v` %d, %v\n", formatTimestamp(started), result.Supply, delay)
+		} else {
+			line += "**missing**\n"
+		}
+
+		if out.Len()+len(line) > embedDescriptionLimit {
+			truncated = len(bench.Results) - i
+			break
+		}
+		out.WriteString(line)
+	}
+
+	if out.Len() == 0 {
+		out.WriteString("The benchmark has no steps.")
+	}
+
+	done, averageDelay := bench.Summary()
+	average, err := formatDelay(bench.Replay, averageDelay)
+	if err != nil {
+		return discordgo.MessageEmbed{}, err
+	}
+
+	embed := discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("%v compared to benchmark %v", bench.PlayerName, name),
+		Description: out.String(),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf(
+				"Took %d of %d steps, %v on average. Starts of units and upgrades are estimated from their build times.",
+				done,
+				len(bench.Results),
+				average,
+			),
+		},
+	}
+
+	if truncated > 0 {
+		appendFooter(&embed, fmt.Sprintf("%d more steps omitted", truncated))
+	}
+
+	return embed, nil
+}
+
+// Describe a delay in loops as being ahead or behind by the ingame duration.
+func formatDelay(replay *sc2replay.Replay, loops int64) (string, error) {
+	abs := loops
+	if abs < 0 {
+		abs = -abs
+	}
+
+	duration, err := replay.DurationAt(abs)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case duration < time.Second:
+		return "on time", nil
+	case loops < 0:
+		return fmt.Sprintf("%v ahead", formatTimestamp(duration)), nil
+	default:
+		return fmt.Sprintf("**%v behind**", formatTimestamp(duration)), nil
+	}
+}
+
+func (bot *Bot) cmdBenchList(ctxt CommandContext) bool {
+	benchmarks, err := persistence.Benchmarks(bot.orm, ctxt.User().ID, ctxt.Guild().ID)
+	if err != nil {
+		ctxt.InternalError(err)
+		return true
+	}
+
+	out := strings.Builder{}
+	for _, benchmark := range benchmarks {
+		owner := "yours"
+		if benchmark.IsGuildBenchmark() {
+			owner = "server"
+		}
+		fmt.Fprintf(&out, "- `%v` (%v): %d steps\n", benchmark.Name, owner, len(benchmark.Steps))
+	}
+	if len(benchmarks) == 0 {
+		out.WriteString("No benchmarks yet. Use `!bench set <name>` to add one.")
+	}
+
+	embed := discordgo.MessageEmbed{
+		Title:       "Benchmarks",
+		Description: truncate(out.String(), embedDescriptionLimit),
+	}
+	ctxt.RespondEmbed(&embed)
+
+	return true
+}
+
+func (bot *Bot) cmdBenchShow(ctxt CommandContext, name string) bool {
+	benchmark, ok, err := persistence.FindBenchmark(bot.orm, name, ctxt.User().ID, ctxt.Guild().ID)
+	if err != nil {
+		ctxt.InternalError(err)
+		return true
+	}
+	if !ok {
+		ctxt.Respond(fmt.Sprintf("No benchmark named '%v' found.", name))
+		return true
+	}
+
+	out := strings.Builder{}
+	for _, step := range benchmark.Steps {
+		fmt.Fprintf(
+			&out,
+			"`%v` %d %v\n",
+			formatTimestamp(time.Duration(step.Seconds)*time.Second),
+			step.Supply,
+			step.Name,
+		)
+	}
+
+	embed := discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Benchmark %v", benchmark.Name),
+		Description: truncate(out.String(), embedDescriptionLimit),
+	}
+	ctxt.RespondEmbed(&embed)
+
+	return true
+}
+
+func (bot *Bot) cmdBenchSet(ctxt CommandContext, name string, guild bool, lines []string) bool {
+	if benchmarkSubcommands[strings.ToLower(name)] {
+		ctxt.Respond(fmt.Sprintf("'%v' cannot be used as the name of a benchmark.", name))
+		return true
+	}
+
+	benchmark, err := bot.benchmarkOwnedBy(ctxt, name, guild)
+	if err != nil {
+		ctxt.Respond(err.Error())
+		return true
+	}
+
+	for i, line := range lines {
+		step, ok, err := parseBenchmarkStep(line)
+		if err != nil {
+			ctxt.Respond(fmt.Sprintf("Line %d: %v", i+2, err))
+			return true
+		}
+		if ok {
+			benchmark.Steps = append(benchmark.Steps, step)
+		}
+	}
+
+	if len(benchmark.Steps) == 0 {
+		ctxt.Respond("Please list the steps of the benchmark on the lines following the command, one per line, as `<time> <supply> <name>`, eg `0:18 14 Pylon`.")
+		return true
+	}
+	if len(benchmark.Steps) > benchmarkMaxSteps {
+		ctxt.Respond(fmt.Sprintf("Benchmarks are limited to %d steps.", benchmarkMaxSteps))
+		return true
+	}
+
+	if err := benchmark.Save(bot.orm); err != nil {
+		ctxt.InternalError(err)
+		return true
+	}
+
+	ctxt.Respond(fmt.Sprintf("Success: Saved benchmark '%v' with %d steps.", benchmark.Name, len(benchmark.Steps)))
+	return true
+}
+
+func (bot *Bot) cmdBenchDelete(ctxt CommandContext, name string, guild bool) bool {
+	benchmark, err := bot.benchmarkOwnedBy(ctxt, name, guild)
+	if err != nil {
+		ctxt.Respond(err.Error())
+		return true
+	}
+
+	deleted, err := benchmark.Delete(bot.orm)
+	if err != nil {
+		ctxt.InternalError(err)
+		return true
+	}
+	if !deleted {
+		ctxt.Respond(fmt.Sprintf("Error: No benchmark named '%v' exists.", name))
+		return true
+	}
+
+	ctxt.Respond(fmt.Sprintf("Success: Deleted benchmark '%v'.", name))
+	return true
+}
+
+// Return an empty benchmark of the given name, belonging to either the user
+// or the guild. Benchmarks of a guild can only be managed by its owner and
+// admins of the bot.
+func (bot *Bot) benchmarkOwnedBy(ctxt CommandContext, name string, guild bool) (persistence.Benchmark, error) {
+	benchmark := persistence.Benchmark{Name: name}
+
+	if !guild {
+		benchmark.DiscordUserID = &ctxt.User().ID
+		return benchmark, nil
+	}
+
+	if ctxt.Guild().DiscordID == persistence.DMGuildDiscordID {
+		return benchmark, fmt.Errorf("Server benchmarks cannot be managed in direct messages.")
+	}
+	if ctxt.Msg().Author.ID != ctxt.Guild().OwnerID && !bot.isAdmin(ctxt.Msg().Author.ID) {
+		return benchmark, fmt.Errorf("Server benchmarks can only be managed by the owner of the server.")
+	}
+
+	benchmark.DiscordGuildID = &ctxt.Guild().ID
+	return benchmark, nil
+}
+
+// Parse a step of a benchmark of the form `<time> <supply> <name>`, where the
+// time is the one at which the step is started. As `!buildorder` lists start
+// times as well, and backticks are ignored, its output can be used as is.
+// Returns false for empty lines.
+func parseBenchmarkStep(line string) (persistence.BenchmarkStep, bool, error) {
+	step := persistence.BenchmarkStep{}
+
+	fields := strings.Fields(strings.ReplaceAll(line, "`", ""))
+	if len(fields) == 0 {
+		return step, false, nil
+	}
+	if len(fields) < 3 {
+		return step, false, fmt.Errorf("Steps must be of format `<time> <supply> <name>`, eg `0:18 14 Pylon`")
+	}
+
+	seconds, err := timestampToSeconds(fields[0])
+	if err != nil {
+		return step, false, err
+	}
+
+	supply, err := strconv.Atoi(fields[1])
+	if err != nil || supply < 0 {
+		return step, false, fmt.Errorf("Supply must be a non-negative number")
+	}
+
+	name := strings.Join(fields[2:], " ")
+	catalogName, ok := units.DefaultCatalog().LookupName(name)
+	if !ok {
+		return step, false, fmt.Errorf("Unknown unit, building or upgrade: %v", name)
+	}
+
+	step.Name = catalogName
+	step.Supply = supply
+	step.Seconds = int64(seconds)
+
+	return step, true, nil
+}
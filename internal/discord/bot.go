@@ -5,6 +5,9 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/dragaera/probius/internal/config"
 	"github.com/dragaera/probius/internal/persistence"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"github.com/dragaera/probius/internal/sc2replay/units"
+	"github.com/gomodule/redigo/redis"
 	"gorm.io/gorm"
 	"log"
 	"os"
@@ -21,18 +24,34 @@ type Bot struct {
 	Session   *discordgo.Session
 	cmdRouter *CommandRouter
 	orm       *gorm.DB
+	cache     *persistence.ReplayCache
 }
 
-func (bot *Bot) Run(orm *gorm.DB) error {
+// Run the bot. If `redis` is nil, results of replay analyses are not cached.
+func (bot *Bot) Run(orm *gorm.DB, redis *redis.Pool) error {
 	if bot.Session == nil {
 		return fmt.Errorf("Bot not initiated, be sure to use discord.Create(...)")
 	}
 
 	bot.orm = orm
+	if redis != nil {
+		cacheVersion := fmt.Sprintf("%v-%d", version, sc2replay.AnalysisVersion)
+		// Results depend on the unit catalog overrides, so changing them
+		// must not serve results generated with the old ones.
+		if hash := units.CatalogOverridesHash(); hash != "" {
+			cacheVersion = fmt.Sprintf("%v-%v", cacheVersion, hash[:16])
+		}
+
+		bot.cache = &persistence.ReplayCache{
+			Redis:   redis,
+			Config:  bot.Config.ReplayCache,
+			Version: cacheVersion,
+		}
+	}
 
 	err := bot.Session.Open()
 	if err != nil {
-		return fmt.Errorf("Error connecting to Discord:", err)
+		return fmt.Errorf("Error connecting to Discord: %v", err)
 	}
 	defer bot.Session.Close()
 
@@ -59,7 +78,7 @@ func Create(bot *Bot) (*Bot, error) {
 	// Create a new Discord session using the provided bot token.
 	dg, err := discordgo.New("Bot " + bot.Config.Discord.Token)
 	if err != nil {
-		return bot, fmt.Errorf("Error creating Discord session:", err)
+		return bot, fmt.Errorf("Error creating Discord session: %v", err)
 	}
 	bot.Session = dg
 
@@ -188,12 +207,135 @@ func (bot *Bot) registerCommands() error {
 		},
 		Command{
 			Command:     "supply",
-			Description: "Parse replay, showing supply details at given timestamp",
-			Usage:       "supply <timestamp>",
+			Description: "Parse replay, showing supply details at given timestamps",
+			Usage:       "supply <timestamp> [timestamp...] [player|slot|all] [--blocks] [--diagnostics] [--mechanics]",
 			MinArgs:     1,
-			MaxArgs:     1,
+			MaxArgs:     12,
 			F:           bot.cmdSupply,
 		},
+		Command{
+			Command:     "buildorder",
+			Aliases:     []string{"bo"},
+			Description: "Parse replay, showing the build order of a player",
+			Usage:       "buildorder [player|slot|all]",
+			MinArgs:     0,
+			MaxArgs:     1,
+			F:           bot.cmdBuildOrder,
+		},
+		Command{
+			Command:     "economy",
+			Aliases:     []string{"eco"},
+			Description: "Parse replay, showing economy details at given timestamp or range",
+			Usage:       "economy <timestamp|from-to> [player|slot|all]",
+			MinArgs:     1,
+			MaxArgs:     2,
+			F:           bot.cmdEconomy,
+		},
+		Command{
+			Command:     "chart",
+			Description: "Parse replay, showing a chart of the given metric over time",
+			Usage:       "chart <supply|workers|army|income|bank> [player|slot|all]",
+			MinArgs:     1,
+			MaxArgs:     2,
+			F:           bot.cmdChart,
+		},
+		Command{
+			Command:     "apm",
+			Description: "Parse replay, showing actions per minute of players",
+			Usage:       "apm [player|slot|all]",
+			MinArgs:     0,
+			MaxArgs:     1,
+			F:           bot.cmdAPM,
+		},
+		Command{
+			Command:     "blocks",
+			Description: "Parse replay, showing when players were supply blocked",
+			Usage:       "blocks [player|slot|all]",
+			MinArgs:     0,
+			MaxArgs:     1,
+			F:           bot.cmdBlocks,
+		},
+		Command{
+			Command:     "bases",
+			Description: "Parse replay, showing when players expanded and lost bases",
+			Usage:       "bases [player|slot|all]",
+			MinArgs:     0,
+			MaxArgs:     1,
+			F:           bot.cmdBases,
+		},
+		Command{
+			Command:     "upgrades",
+			Description: "Parse replay, showing when players started and finished researching upgrades",
+			Usage:       "upgrades [player|slot|all]",
+			MinArgs:     0,
+			MaxArgs:     1,
+			F:           bot.cmdUpgrades,
+		},
+		Command{
+			Command:     "mechanics",
+			Description: "Parse replay, showing how players used injects, chronoboost, MULEs and scans",
+			Usage:       "mechanics [player|slot|all]",
+			MinArgs:     0,
+			MaxArgs:     1,
+			F:           bot.cmdMechanics,
+		},
+		Command{
+			Command:     "bench",
+			Description: "Compare the build order of a player to a stored benchmark, or manage benchmarks. Steps of a benchmark follow on separate lines, as `<time> <supply> <name>`",
+			Usage:       "bench <name> [player|slot|all] | bench set <name> [--guild] | bench list | bench show <name> | bench delete <name> [--guild]",
+			MinArgs:     1,
+			MaxArgs:     -1,
+			F:           bot.cmdBench,
+		},
+		Command{
+			Command:     "fights",
+			Description: "Parse replay, listing major fights and who won them",
+			Usage:       "fights [min_resources_lost]",
+			MinArgs:     0,
+			MaxArgs:     1,
+			F:           bot.cmdFights,
+		},
+		Command{
+			Command:     "heatmap",
+			Description: "Parse replay, showing where a player's army spent its time",
+			Usage:       "heatmap [player|slot|all]",
+			MinArgs:     0,
+			MaxArgs:     1,
+			F:           bot.cmdHeatmap,
+		},
+		Command{
+			Command:     "chat",
+			Description: "Parse replay, showing chat messages and pings. Use `--public` to hide team chat",
+			Usage:       "chat [--public]",
+			MinArgs:     0,
+			MaxArgs:     1,
+			F:           bot.cmdChat,
+		},
+		Command{
+			Command:     "analyze",
+			Description: "Parse replay, showing a summary of the game without requiring SC2Replaystats",
+			Usage:       "analyze",
+			MinArgs:     0,
+			MaxArgs:     0,
+			F:           bot.cmdAnalyze,
+		},
+		Command{
+			Command:     "critters",
+			Description: "Parse replay, showing who killed which critters, or show the guild's best critter hunters",
+			Usage:       "critters [leaderboard]",
+			MinArgs:     0,
+			MaxArgs:     1,
+			F:           bot.cmdCritters,
+		},
+		Command{
+			Command:     "unknown",
+			Description: "List units and upgrades missing from the unit catalog, across all analysed replays. Admins only",
+			Usage:       "unknown",
+			MinArgs:     0,
+			MaxArgs:     0,
+			Middleware:  []Middleware{bot.requireAdmin},
+			F:           bot.cmdUnknown,
+		},
 	}
 
 	for _, cmd := range commands {
@@ -0,0 +1,94 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"strings"
+)
+
+func (bot *Bot) cmdBuildOrder(ctxt CommandContext) bool {
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		selector := ""
+		if len(ctxt.Args()) > 0 {
+			selector = ctxt.Args()[0]
+		}
+
+		playerIDs, err := selectPlayers(replay, selector)
+		if err != nil {
+			ctxt.Respond(err.Error())
+			return
+		}
+
+		// Build orders of all players are generated in a single pass.
+		buildOrders := make([]*sc2replay.BuildOrder, 0, len(playerIDs))
+		observers := make([]sc2replay.Observer, 0, len(playerIDs))
+		for _, playerID := range playerIDs {
+			buildOrder := &sc2replay.BuildOrder{
+				PlayerID: playerID,
+				Replay:   replay,
+			}
+			buildOrders = append(buildOrders, buildOrder)
+			observers = append(observers, buildOrder)
+		}
+
+		if err := sc2replay.Analyse(replay, observers...); err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		for _, buildOrder := range buildOrders {
+			embed, err := buildBuildOrderEmbed(buildOrder)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+			ctxt.RespondEmbed(&embed)
+		}
+	})
+
+	return true
+}
+
+func buildBuildOrderEmbed(buildOrder *sc2replay.BuildOrder) (discordgo.MessageEmbed, error) {
+	out := strings.Builder{}
+	truncated := 0
+
+	for i, item := range buildOrder.Items {
+		ts, err := buildOrder.Replay.DurationAt(item.StartLoop)
+		if err != nil {
+			return discordgo.MessageEmbed{}, err
+		}
+
+		line := fmt.Sprintf(
+			"`%v` %d %v\n",
+			formatTimestamp(ts),
+			item.IngameSupply(),
+			item.Name,
+		)
+
+		if out.Len()+len(line) > embedDescriptionLimit {
+			truncated = len(buildOrder.Items) - i
+			break
+		}
+		out.WriteString(line)
+	}
+
+	if out.Len() == 0 {
+		out.WriteString("Nothing was built :(")
+	}
+
+	embed := discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Build order of %v", buildOrder.PlayerName),
+		Description: out.String(),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Times and supply are as of when items were started. Starts of units are estimated from their build times, the ones of upgrades taken from the commands starting them where found.",
+		},
+	}
+
+	if truncated > 0 {
+		appendFooter(&embed, fmt.Sprintf("%d more steps omitted", truncated))
+	}
+
+	return embed, nil
+}
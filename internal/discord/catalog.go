@@ -0,0 +1,143 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/persistence"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"log"
+	"strings"
+)
+
+// Maximum length of an embed's footer, as per Discord's API.
+const embedFooterLimit int = 2048
+
+const unknownCatalogNamesSize int = 25
+
+// Names of units and upgrades missing from the unit catalog, across a set of
+// reports.
+type unknownNames struct {
+	Units    []string
+	Upgrades []string
+}
+
+func collectUnknownNames(reports []sc2replay.Report) unknownNames {
+	units := make(map[string]bool)
+	upgrades := make(map[string]bool)
+	names := unknownNames{}
+
+	for _, report := range reports {
+		for _, name := range report.UnknownUnits {
+			if !units[name] {
+				units[name] = true
+				names.Units = append(names.Units, name)
+			}
+		}
+		for _, name := range report.UnknownUpgrades {
+			if !upgrades[name] {
+				upgrades[name] = true
+				names.Upgrades = append(names.Upgrades, name)
+			}
+		}
+	}
+
+	return names
+}
+
+func (names *unknownNames) empty() bool {
+	return len(names.Units) == 0 && len(names.Upgrades) == 0
+}
+
+// Add a footer warning about units and upgrades which were not accounted
+// for, if there are any.
+func addUnknownNamesFooter(embed *discordgo.MessageEmbed, names unknownNames) {
+	if names.empty() {
+		return
+	}
+
+	all := append(append([]string{}, names.Units...), names.Upgrades...)
+	appendFooter(embed, fmt.Sprintf("⚠ Not accounted for, as unknown to Probius: %v", strings.Join(all, ", ")))
+}
+
+// Persist names missing from the unit catalog, so they can be reviewed with
+// `!unknown`. Failures are logged, but otherwise ignored.
+func (bot *Bot) recordUnknownNames(gameID string, baseBuild int64, names unknownNames) {
+	records := make([]persistence.UnknownCatalogName, 0, len(names.Units)+len(names.Upgrades))
+	for _, name := range names.Units {
+		records = append(records, persistence.UnknownCatalogName{
+			Kind: persistence.UnknownCatalogUnit,
+			Name: name,
+		})
+	}
+	for _, name := range names.Upgrades {
+		records = append(records, persistence.UnknownCatalogName{
+			Kind: persistence.UnknownCatalogUpgrade,
+			Name: name,
+		})
+	}
+
+	for _, record := range records {
+		record.GameID = gameID
+		record.BaseBuild = baseBuild
+		if err := record.Record(bot.orm); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+func (bot *Bot) cmdUnknown(ctxt CommandContext) bool {
+	entries, err := persistence.UnknownCatalogNames(bot.orm, unknownCatalogNamesSize)
+	if err != nil {
+		ctxt.InternalError(err)
+		return true
+	}
+
+	out := strings.Builder{}
+	for _, entry := range entries {
+		builds := fmt.Sprintf("%d", entry.MinBuild)
+		if entry.MaxBuild != entry.MinBuild {
+			builds = fmt.Sprintf("%d-%d", entry.MinBuild, entry.MaxBuild)
+		}
+
+		fmt.Fprintf(
+			&out,
+			"- %v `%v`: %d games, builds %v\n",
+			entry.Kind,
+			entry.Name,
+			entry.Games,
+			builds,
+		)
+	}
+	if len(entries) == 0 {
+		out.WriteString("No unknown units or upgrades encountered so far.")
+	}
+
+	embed := discordgo.MessageEmbed{
+		Title:       "Missing from unit catalog",
+		Description: truncate(out.String(), embedDescriptionLimit),
+	}
+	ctxt.RespondEmbed(&embed)
+
+	return true
+}
+
+// Restrict a command to the users configured as admins.
+func (bot *Bot) requireAdmin(cmd Command, ctxt CommandContext) (CommandContext, error) {
+	if bot.isAdmin(ctxt.Msg().Author.ID) {
+		return ctxt, nil
+	}
+
+	ctxt.Respond("This command is restricted to admins of the bot.")
+	return ctxt, fmt.Errorf("User %v is not an admin", ctxt.Msg().Author.ID)
+}
+
+// Whether the Discord user is configured as an admin of the bot.
+func (bot *Bot) isAdmin(discordID string) bool {
+	for _, id := range bot.Config.Discord.AdminIDs {
+		if id == discordID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/chart"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"sort"
+	"strings"
+	"time"
+)
+
+const chartWidth int = 800
+const chartHeight int = 400
+
+func (bot *Bot) cmdChart(ctxt CommandContext) bool {
+	metricID := strings.ToLower(ctxt.Args()[0])
+	metric, ok := sc2replay.EconomyMetrics[metricID]
+	if !ok {
+		ctxt.Respond(fmt.Sprintf("Unknown metric: `%v`. Available metrics: %v", metricID, economyMetricList()))
+		return true
+	}
+
+	// Charts are most useful when comparing players
+	selector := "all"
+	if len(ctxt.Args()) > 1 {
+		selector = ctxt.Args()[1]
+	}
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		playerIDs, err := selectPlayers(replay, selector)
+		if err != nil {
+			ctxt.Respond(err.Error())
+			return
+		}
+
+		timeline := sc2replay.EconomyTimeline{Replay: replay}
+		if err := timeline.Generate(); err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		lineChart := chart.LineChart{
+			Title:  metric.Name,
+			Width:  chartWidth,
+			Height: chartHeight,
+			FormatX: func(seconds float64) string {
+				return formatTimestamp(time.Duration(seconds * float64(time.Second)))
+			},
+		}
+
+		for _, playerID := range playerIDs {
+			series, err := buildEconomySeries(replay, &timeline, playerID, metric)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+			lineChart.Series = append(lineChart.Series, series)
+		}
+
+		buf := bytes.Buffer{}
+		if err := lineChart.Render(&buf); err != nil {
+			ctxt.InternalError(fmt.Errorf("Unable to render chart: %v", err))
+			return
+		}
+
+		fileName := fmt.Sprintf("%v.png", metricID)
+		embed := discordgo.MessageEmbed{
+			Title: fmt.Sprintf("%v over time", metric.Name),
+			Image: &discordgo.MessageEmbedImage{
+				URL: fmt.Sprintf("attachment://%v", fileName),
+			},
+		}
+		files := []*discordgo.File{
+			&discordgo.File{
+				Name:        fileName,
+				ContentType: "image/png",
+				Reader:      &buf,
+			},
+		}
+		ctxt.RespondEmbedWithFiles(&embed, files)
+	})
+
+	return true
+}
+
+// Build a chart series of the given metric, with the game time in seconds as
+// X value.
+func buildEconomySeries(replay *sc2replay.Replay, timeline *sc2replay.EconomyTimeline, playerID int64, metric sc2replay.EconomyMetric) (chart.Series, error) {
+	series := chart.Series{}
+
+	name, err := replay.PlayerName(playerID)
+	if err != nil {
+		return series, err
+	}
+	series.Name = name
+
+	for _, sample := range timeline.Samples[playerID] {
+		ts, err := replay.DurationAt(sample.Loop)
+		if err != nil {
+			return series, err
+		}
+
+		series.Points = append(series.Points, chart.Point{
+			X: ts.Seconds(),
+			Y: metric.Value(sample),
+		})
+	}
+
+	return series, nil
+}
+
+func economyMetricList() string {
+	ids := make([]string, 0, len(sc2replay.EconomyMetrics))
+	for id := range sc2replay.EconomyMetrics {
+		ids = append(ids, fmt.Sprintf("`%v`", id))
+	}
+	sort.Strings(ids)
+
+	return strings.Join(ids, ", ")
+}
@@ -0,0 +1,97 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"strings"
+)
+
+// Maximum length of a message, as per Discord's API.
+const messageLimit int = 2000
+
+func (bot *Bot) cmdChat(ctxt CommandContext) bool {
+	_, options := splitOptions(ctxt.Args())
+	publicOnly := options["public"]
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		messages, err := replay.Messages()
+		if err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		lines := make([]string, 0, len(messages))
+		for _, msg := range messages {
+			if publicOnly && msg.Private() {
+				continue
+			}
+
+			ts, err := replay.DurationAt(msg.Loop)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			text := msg.Text
+			if msg.Kind == sc2replay.MessagePing {
+				text = "*pinged the minimap*"
+			}
+
+			lines = append(lines, fmt.Sprintf(
+				"`%v` [%v] **%v**: %v",
+				formatTimestamp(ts),
+				recipientName(msg.Recipient),
+				escapeMentions(msg.Sender),
+				escapeMentions(text),
+			))
+		}
+
+		if len(lines) == 0 {
+			ctxt.Respond("Nobody said anything.")
+			return
+		}
+
+		respondInChunks(ctxt, lines)
+	})
+
+	return true
+}
+
+func recipientName(recipient int64) string {
+	switch recipient {
+	case events.RecipientAll:
+		return "All"
+	case events.RecipientAllies:
+		return "Allies"
+	case events.RecipientObservers:
+		return "Observers"
+	default:
+		return "Private"
+	}
+}
+
+// Prevent text taken from replays from pinging anyone, eg via `@everyone`, by
+// inserting a zero-width space.
+func escapeMentions(text string) string {
+	return strings.Replace(text, "@", "@\u200b", -1)
+}
+
+// Respond with the given lines, split across as many messages as needed.
+func respondInChunks(ctxt CommandContext, lines []string) {
+	out := strings.Builder{}
+
+	for _, line := range lines {
+		line = truncate(line, messageLimit-1)
+		if out.Len()+len(line)+1 > messageLimit {
+			ctxt.Respond(out.String())
+			out.Reset()
+		}
+		out.WriteString(line)
+		out.WriteString("\n")
+	}
+
+	if out.Len() > 0 {
+		ctxt.Respond(out.String())
+	}
+}
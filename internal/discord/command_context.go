@@ -7,6 +7,12 @@ import (
 	"log"
 )
 
+// Maximum length of an embed's description, as per Discord's API.
+const embedDescriptionLimit int = 2048
+
+// Maximum length of an embed field's value, as per Discord's API.
+const embedFieldLimit int = 1024
+
 type CommandContext interface {
 	SetSess(*discordgo.Session)
 	Sess() *discordgo.Session
@@ -28,6 +34,7 @@ type CommandContext interface {
 
 	Respond(string) error
 	RespondEmbed(*discordgo.MessageEmbed) error
+	RespondEmbedWithFiles(*discordgo.MessageEmbed, []*discordgo.File) error
 	InternalError(error) error
 }
 
@@ -108,6 +115,24 @@ func (ctxt *BaseCommandContext) RespondEmbed(embed *discordgo.MessageEmbed) erro
 	return err
 }
 
+// Respond with an embed, attaching files to the message. The embed can refer
+// to attached files as `attachment://<name>`, eg to show them as its image.
+func (ctxt *BaseCommandContext) RespondEmbedWithFiles(embed *discordgo.MessageEmbed, files []*discordgo.File) error {
+	_, err := ctxt.Sess().ChannelMessageSendComplex(
+		ctxt.Msg().ChannelID,
+		&discordgo.MessageSend{
+			Embed: embed,
+			Files: files,
+		},
+	)
+
+	if err != nil {
+		log.Printf("Error while responding with embed and files: %v", err)
+	}
+
+	return err
+}
+
 func (ctxt *BaseCommandContext) InternalError(err error) error {
 	msg := fmt.Sprintf(
 		"An internal error has happened while performing this operation.\nPlease report the following to 'Morrolan#3163':\n`%v`",
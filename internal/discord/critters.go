@@ -0,0 +1,113 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/persistence"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"log"
+	"strings"
+)
+
+const critterLeaderboardSize int = 10
+
+func (bot *Bot) cmdCritters(ctxt CommandContext) bool {
+	if len(ctxt.Args()) > 0 {
+		if ctxt.Args()[0] != "leaderboard" {
+			return false
+		}
+
+		return bot.cmdCritterLeaderboard(ctxt)
+	}
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		ownerID, err := replay.OwnerPlayerID()
+		if err != nil {
+			ctxt.Respond(fmt.Sprintf("Unable to determine owner player ID: %v", err))
+			return
+		}
+
+		report := sc2replay.Report{PlayerID: ownerID, Replay: replay}
+		report.At(replay.Rep.Header.Loops())
+
+		reports := []sc2replay.Report{report}
+		bot.recordCritterKills(ctxt, replay.GameID(), ownerCritterKills(replay, reports))
+		bot.recordUnknownNames(replay.GameID(), replay.Rep.Header.BaseBuild(), collectUnknownNames(reports))
+
+		embed := discordgo.MessageEmbed{
+			Title:       "Critter report",
+			Description: buildCritterList(&report),
+		}
+		ctxt.RespondEmbed(&embed)
+	})
+
+	return true
+}
+
+func (bot *Bot) cmdCritterLeaderboard(ctxt CommandContext) bool {
+	entries, err := persistence.CritterLeaderboard(bot.orm, ctxt.Guild().ID, critterLeaderboardSize)
+	if err != nil {
+		ctxt.InternalError(err)
+		return true
+	}
+
+	out := strings.Builder{}
+	for i, entry := range entries {
+		fmt.Fprintf(&out, "%d. %v: %d\n", i+1, escapeMentions(entry.Name), entry.Kills)
+	}
+	if len(entries) == 0 {
+		out.WriteString("Nobody has killed any critters yet. Post a replay with `!critters` to get started!")
+	}
+
+	embed := discordgo.MessageEmbed{
+		Title:       "Critter hunters",
+		Description: out.String(),
+	}
+	ctxt.RespondEmbed(&embed)
+
+	return true
+}
+
+// Return the critters killed by the replay's owner, provided the owner is one
+// of the players the reports are of.
+func ownerCritterKills(replay *sc2replay.Replay, reports []sc2replay.Report) []sc2replay.CritterKill {
+	kills := make([]sc2replay.CritterKill, 0)
+
+	ownerID, err := replay.OwnerPlayerID()
+	if err != nil {
+		return kills
+	}
+
+	for _, report := range reports {
+		if report.PlayerID != ownerID {
+			continue
+		}
+
+		for _, kill := range report.CritterKills {
+			if kill.KillerPlayerID == ownerID {
+				kills = append(kills, kill)
+			}
+		}
+	}
+
+	return kills
+}
+
+// Persist critters killed by the replay's owner, crediting them to the user
+// who posted the replay. Failures are logged, but otherwise ignored.
+func (bot *Bot) recordCritterKills(ctxt CommandContext, gameID string, kills []sc2replay.CritterKill) {
+	for _, kill := range kills {
+		record := persistence.CritterKill{
+			DiscordUserID:  ctxt.User().ID,
+			DiscordGuildID: ctxt.Guild().ID,
+			GameID:         gameID,
+			Loop:           kill.Loop,
+			UnitTag:        kill.Tag,
+			Critter:        kill.Critter.Name,
+			KillerUnit:     kill.KillerUnit,
+		}
+		if err := record.Record(bot.orm); err != nil {
+			log.Print(err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"strings"
+)
+
+// Collect warnings and errors encountered while processing the replay and
+// generating the reports. Reports of multiple players share most of them.
+func collectDiagnostics(replay *sc2replay.Replay, reports []sc2replay.Report) []sc2replay.Diagnostic {
+	diags := sc2replay.Diagnostics{}
+	diags.Merge(replay.Diagnostics.AtLeast(sc2replay.SeverityWarning))
+	for i := range reports {
+		diags.Merge(reports[i].Diagnostics.AtLeast(sc2replay.SeverityWarning))
+	}
+
+	return diags.Entries
+}
+
+// Add a footer warning that results might be inaccurate, if there were any
+// problems.
+func addDiagnosticsFooter(embed *discordgo.MessageEmbed, diags []sc2replay.Diagnostic) {
+	switch len(diags) {
+	case 0:
+		return
+	case 1:
+		appendFooter(embed, fmt.Sprintf("⚠ Results might be inaccurate: %v", diags[0].Message))
+	default:
+		appendFooter(embed, fmt.Sprintf(
+			"⚠ Results might be inaccurate, as %d problems were encountered while processing the replay. Use --diagnostics for details.",
+			len(diags),
+		))
+	}
+}
+
+// List diagnostics, along with the ingame time of the events causing them.
+func buildDiagnosticsList(replay *sc2replay.Replay, diags []sc2replay.Diagnostic) string {
+	out := strings.Builder{}
+
+	for _, diag := range diags {
+		fmt.Fprintf(&out, "- %v", diag.Severity)
+		if len(diag.EventType) > 0 {
+			fmt.Fprintf(&out, ", %v", diag.EventType)
+			if ts, err := replay.DurationAt(diag.Loop); err == nil {
+				fmt.Fprintf(&out, " at `%v`", formatTimestamp(ts))
+			}
+		}
+		fmt.Fprintf(&out, ": %v\n", diag.Message)
+	}
+
+	if out.Len() == 0 {
+		out.WriteString("No problems encountered.")
+	}
+
+	return truncate(out.String(), embedFieldLimit)
+}
+
+// Append a line to the embed's footer.
+func appendFooter(embed *discordgo.MessageEmbed, text string) {
+	if embed.Footer == nil {
+		embed.Footer = &discordgo.MessageEmbedFooter{}
+	} else {
+		embed.Footer.Text += "\n"
+	}
+
+	embed.Footer.Text = truncate(embed.Footer.Text+text, embedFooterLimit)
+}
@@ -0,0 +1,144 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"strings"
+)
+
+func (bot *Bot) cmdEconomy(ctxt CommandContext) bool {
+	ts := ctxt.Args()[0]
+	from, to, err := timestampRangeToSeconds(ts)
+	if err != nil {
+		ctxt.Respond(err.Error())
+		return true
+	}
+
+	selector := ""
+	if len(ctxt.Args()) > 1 {
+		selector = ctxt.Args()[1]
+	}
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		playerIDs, err := selectPlayers(replay, selector)
+		if err != nil {
+			ctxt.Respond(err.Error())
+			return
+		}
+
+		timeline := sc2replay.EconomyTimeline{Replay: replay}
+		if err := timeline.Generate(); err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		fromTicks, err := replay.TicksUntilSeconds(float64(from))
+		if err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+		toTicks, err := replay.TicksUntilSeconds(float64(to))
+		if err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		fields := make([]*discordgo.MessageEmbedField, 0, len(playerIDs))
+		for _, playerID := range playerIDs {
+			name, err := replay.PlayerName(playerID)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			var value string
+			if from == to {
+				value = buildEconomySample(&timeline, playerID, toTicks)
+			} else {
+				value = buildEconomySummary(&timeline, playerID, fromTicks, toTicks)
+			}
+
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:   name,
+				Value:  value,
+				Inline: true,
+			})
+		}
+
+		embed := discordgo.MessageEmbed{
+			Title:  fmt.Sprintf("Economy report at %v", ts),
+			Fields: fields,
+		}
+		ctxt.RespondEmbed(&embed)
+	})
+
+	return true
+}
+
+// Parse either a single timestamp, or a range of timestamps of the form
+// `<from>-<to>`. For single timestamps, both return values are equal.
+func timestampRangeToSeconds(timestamp string) (int, int, error) {
+	parts := strings.Split(timestamp, "-")
+
+	switch len(parts) {
+	case 1:
+		seconds, err := timestampToSeconds(parts[0])
+		return seconds, seconds, err
+	case 2:
+		from, err := timestampToSeconds(parts[0])
+		if err != nil {
+			return 0, 0, err
+		}
+
+		to, err := timestampToSeconds(parts[1])
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if to < from {
+			return 0, 0, fmt.Errorf("End of range must not be before its start")
+		}
+
+		return from, to, nil
+	default:
+		return 0, 0, fmt.Errorf("Range must be of format [HH:]MM:SS-[HH:]MM:SS")
+	}
+}
+
+func buildEconomySample(timeline *sc2replay.EconomyTimeline, playerID int64, ticks int64) string {
+	sample, ok := timeline.At(playerID, ticks)
+	if !ok {
+		return "No data available"
+	}
+
+	out := strings.Builder{}
+	fmt.Fprintf(&out, "**Workers**: %d\n", sample.Workers)
+	fmt.Fprintf(&out, "**Collection rate**: %d/%d\n", sample.MineralsCollectionRate, sample.VespeneCollectionRate)
+	fmt.Fprintf(&out, "**Bank**: %d/%d\n", sample.MineralsCurrent, sample.VespeneCurrent)
+	fmt.Fprintf(&out, "**Army value**: %d/%d\n", sample.ArmyValueMinerals, sample.ArmyValueVespene)
+
+	return out.String()
+}
+
+func buildEconomySummary(timeline *sc2replay.EconomyTimeline, playerID int64, fromTicks int64, toTicks int64) string {
+	summary, err := timeline.Summarise(playerID, fromTicks, toTicks)
+	if err != nil {
+		return "No data available"
+	}
+
+	out := strings.Builder{}
+	fmt.Fprintf(&out, "**Workers**: %d => %d (avg %.0f)\n", summary.From.Workers, summary.To.Workers, summary.AverageWorkers)
+	fmt.Fprintf(&out, "**Avg collection rate**: %.0f/%.0f\n", summary.AverageMineralsCollectionRate, summary.AverageVespeneCollectionRate)
+	fmt.Fprintf(&out, "**Avg bank**: %.0f/%.0f\n", summary.AverageMineralsCurrent, summary.AverageVespeneCurrent)
+	fmt.Fprintf(
+		&out,
+		"**Army value**: %d/%d => %d/%d\n",
+		summary.From.ArmyValueMinerals,
+		summary.From.ArmyValueVespene,
+		summary.To.ArmyValueMinerals,
+		summary.To.ArmyValueVespene,
+	)
+
+	return out.String()
+}
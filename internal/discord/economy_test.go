@@ -0,0 +1,40 @@
+package discord
+
+import "testing"
+
+func TestTimestampRangeToSeconds(t *testing.T) {
+	tests := []struct {
+		timestamp string
+		wantFrom  int
+		wantTo    int
+		wantErr   bool
+	}{
+		{timestamp: "5:30", wantFrom: 330, wantTo: 330},
+		{timestamp: "5:00-6:00", wantFrom: 300, wantTo: 360},
+		{timestamp: "5:00-5:00", wantFrom: 300, wantTo: 300},
+		{timestamp: "59:00-1:01:00", wantFrom: 3540, wantTo: 3660},
+		{timestamp: "6:00-5:00", wantErr: true},
+		{timestamp: "5:00-", wantErr: true},
+		{timestamp: "-5:00", wantErr: true},
+		{timestamp: "5:00-6:00-7:00", wantErr: true},
+		{timestamp: "5:00-6:60", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.timestamp, func(t *testing.T) {
+			from, to, err := timestampRangeToSeconds(test.timestamp)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("timestampRangeToSeconds(%q) = %d, %d, want error", test.timestamp, from, to)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("timestampRangeToSeconds(%q) failed: %v", test.timestamp, err)
+			}
+			if from != test.wantFrom || to != test.wantTo {
+				t.Errorf("timestampRangeToSeconds(%q) = %d, %d, want %d, %d", test.timestamp, from, to, test.wantFrom, test.wantTo)
+			}
+		})
+	}
+}
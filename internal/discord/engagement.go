@@ -0,0 +1,142 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Minimum amount of resources lost for a fight to be considered major, unless
+// specified otherwise.
+const defaultFightThreshold int64 = 500
+
+// Maximum amount of fields of an embed, as per Discord's API.
+const embedFieldCountLimit int = 25
+
+func (bot *Bot) cmdFights(ctxt CommandContext) bool {
+	threshold := defaultFightThreshold
+	if len(ctxt.Args()) > 0 {
+		var err error
+		threshold, err = strconv.ParseInt(ctxt.Args()[0], 10, 64)
+		if err != nil || threshold < 0 {
+			ctxt.Respond(fmt.Sprintf("Invalid threshold: %v. Must be a positive number", ctxt.Args()[0]))
+			return true
+		}
+	}
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		engagements := sc2replay.Engagements{Replay: replay}
+		if err := engagements.Generate(); err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		fields := make([]*discordgo.MessageEmbedField, 0)
+		omitted := 0
+		for _, engagement := range engagements.Engagements {
+			if engagement.ResourcesLost() < threshold {
+				continue
+			}
+			if len(fields) >= embedFieldCountLimit {
+				omitted += 1
+				continue
+			}
+
+			field, err := buildEngagementField(replay, engagement)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+			fields = append(fields, field)
+		}
+
+		embed := discordgo.MessageEmbed{
+			Title:  "Fights",
+			Fields: fields,
+		}
+		if len(fields) == 0 {
+			embed.Description = fmt.Sprintf("No fights with at least %d resources lost", threshold)
+		}
+		if omitted > 0 {
+			embed.Footer = &discordgo.MessageEmbedFooter{
+				Text: fmt.Sprintf("%d more fights omitted", omitted),
+			}
+		}
+		ctxt.RespondEmbed(&embed)
+	})
+
+	return true
+}
+
+func buildEngagementField(replay *sc2replay.Replay, engagement *sc2replay.Engagement) (*discordgo.MessageEmbedField, error) {
+	start, err := replay.DurationAt(engagement.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := replay.DurationAt(engagement.End)
+	if err != nil {
+		return nil, err
+	}
+
+	playerIDs := make([]int64, 0, len(engagement.Losses))
+	for playerID := range engagement.Losses {
+		playerIDs = append(playerIDs, playerID)
+	}
+	sort.Slice(playerIDs, func(i, j int) bool { return playerIDs[i] < playerIDs[j] })
+
+	out := strings.Builder{}
+	for _, playerID := range playerIDs {
+		losses := engagement.Losses[playerID]
+		name, err := replay.PlayerName(playerID)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&out, "**%v** lost %d resources", name, losses.Resources)
+		if len(losses.Units) > 0 {
+			fmt.Fprintf(&out, ": %v", buildUnitCountList(losses.Units))
+		}
+		out.WriteString("\n")
+	}
+
+	if winnerID, ok := engagement.Winner(); ok {
+		name, err := replay.PlayerName(winnerID)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&out, "Trade won by **%v**", name)
+	} else {
+		out.WriteString("Even trade")
+	}
+
+	field := discordgo.MessageEmbedField{
+		Name:  fmt.Sprintf("%v - %v", formatTimestamp(start), formatTimestamp(end)),
+		Value: truncate(out.String(), embedFieldLimit),
+	}
+
+	return &field, nil
+}
+
+// Format counts by name as eg `3 Marine, 1 Medivac`, most numerous first.
+func buildUnitCountList(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%d %v", counts[name], name)
+	}
+
+	return strings.Join(parts, ", ")
+}
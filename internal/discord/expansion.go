@@ -0,0 +1,111 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"strings"
+)
+
+func (bot *Bot) cmdBases(ctxt CommandContext) bool {
+	selector := "all"
+	if len(ctxt.Args()) > 0 {
+		selector = ctxt.Args()[0]
+	}
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		playerIDs, err := selectPlayers(replay, selector)
+		if err != nil {
+			ctxt.Respond(err.Error())
+			return
+		}
+
+		expansions := sc2replay.Expansions{Replay: replay}
+		if err := expansions.Generate(); err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		fields := make([]*discordgo.MessageEmbedField, 0, len(playerIDs))
+		for _, playerID := range playerIDs {
+			name, err := replay.PlayerName(playerID)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			list, err := buildExpansionList(replay, expansions.Mains[playerID], expansions.Expansions[playerID])
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:   name,
+				Value:  list,
+				Inline: true,
+			})
+		}
+
+		embed := discordgo.MessageEmbed{
+			Title:  "Expansion timings",
+			Fields: fields,
+		}
+		ctxt.RespondEmbed(&embed)
+	})
+
+	return true
+}
+
+func buildExpansionList(replay *sc2replay.Replay, main *sc2replay.Expansion, expansions []*sc2replay.Expansion) (string, error) {
+	out := strings.Builder{}
+
+	// The main is only listed once lost, as its start is the game's.
+	if main != nil && main.Lost > 0 {
+		lost, err := replay.DurationAt(main.Lost)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&out, "1. %v (main): **lost %v**\n", main.Name, formatTimestamp(lost))
+	}
+
+	for i, expansion := range expansions {
+		started, err := replay.DurationAt(expansion.Started)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&out, "%d. %v: started %v", i+2, expansion.Name, formatTimestamp(started))
+
+		if expansion.Finished > 0 {
+			finished, err := replay.DurationAt(expansion.Finished)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&out, ", finished %v", formatTimestamp(finished))
+		}
+
+		if expansion.Lost > 0 {
+			lost, err := replay.DurationAt(expansion.Lost)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&out, ", **lost %v**", formatTimestamp(lost))
+		}
+
+		if expansion.Cancelled > 0 {
+			cancelled, err := replay.DurationAt(expansion.Cancelled)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&out, ", cancelled %v", formatTimestamp(cancelled))
+		}
+
+		out.WriteString("\n")
+	}
+
+	if len(expansions) == 0 {
+		out.WriteString("No expansions")
+	}
+
+	return truncate(out.String(), embedFieldLimit), nil
+}
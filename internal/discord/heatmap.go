@@ -0,0 +1,74 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/chart"
+	"github.com/dragaera/probius/internal/sc2replay"
+)
+
+// Size of a map cell in pixels. Maps are roughly 150-200 cells wide.
+const heatmapScale int = 4
+
+func (bot *Bot) cmdHeatmap(ctxt CommandContext) bool {
+	selector := ""
+	if len(ctxt.Args()) > 0 {
+		selector = ctxt.Args()[0]
+	}
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		playerIDs, err := selectPlayers(replay, selector)
+		if err != nil {
+			ctxt.Respond(err.Error())
+			return
+		}
+
+		for _, playerID := range playerIDs {
+			name, err := replay.PlayerName(playerID)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			heatmap := sc2replay.Heatmap{PlayerID: playerID, Replay: replay}
+			if err := heatmap.Generate(); err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			image := chart.Heatmap{
+				Title:  fmt.Sprintf("Army positions of %v", name),
+				Width:  heatmap.Width,
+				Height: heatmap.Height,
+				Values: heatmap.Counts,
+				Scale:  heatmapScale,
+			}
+
+			buf := bytes.Buffer{}
+			if err := image.Render(&buf); err != nil {
+				ctxt.InternalError(fmt.Errorf("Unable to render heatmap: %v", err))
+				return
+			}
+
+			fileName := fmt.Sprintf("heatmap_%d.png", playerID)
+			embed := discordgo.MessageEmbed{
+				Title:       fmt.Sprintf("Heatmap of %v", name),
+				Description: fmt.Sprintf("Map: %v", replay.Rep.Details.Title()),
+				Image: &discordgo.MessageEmbedImage{
+					URL: fmt.Sprintf("attachment://%v", fileName),
+				},
+			}
+			files := []*discordgo.File{
+				&discordgo.File{
+					Name:        fileName,
+					ContentType: "image/png",
+					Reader:      &buf,
+				},
+			}
+			ctxt.RespondEmbedWithFiles(&embed, files)
+		}
+	})
+
+	return true
+}
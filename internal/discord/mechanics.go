@@ -0,0 +1,251 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"github.com/dragaera/probius/internal/sc2replay/units"
+	"sort"
+	"strings"
+)
+
+func (bot *Bot) cmdMechanics(ctxt CommandContext) bool {
+	selector := "all"
+	if len(ctxt.Args()) > 0 {
+		selector = ctxt.Args()[0]
+	}
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		playerIDs, err := selectPlayers(replay, selector)
+		if err != nil {
+			ctxt.Respond(err.Error())
+			return
+		}
+
+		mech := sc2replay.Mechanics{Replay: replay}
+		if err := mech.Calculate(-1); err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		fields := make([]*discordgo.MessageEmbedField, 0, len(playerIDs))
+		for _, playerID := range playerIDs {
+			name, err := replay.PlayerName(playerID)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			list, err := buildMechanicsList(replay, &mech, playerID)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:   name,
+				Value:  list,
+				Inline: true,
+			})
+		}
+
+		embed := discordgo.MessageEmbed{
+			Title:  "Mechanics",
+			Fields: fields,
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: "Injects and chronoboosts are counted as commanded, even if they were never cast. Inject uptime is measured from a hatchery's first inject. Chronoboosts and scans are attributed to the selected building which cast least recently.",
+			},
+		}
+		appendGuessedMechanicsFooter(&embed, &mech)
+		ctxt.RespondEmbed(&embed)
+	})
+
+	return true
+}
+
+// Add a field with the mechanics of each report's player. The mechanics must
+// have been calculated up until the reports' timestamp.
+func addMechanicsFields(embed *discordgo.MessageEmbed, replay *sc2replay.Replay, mech *sc2replay.Mechanics, reports []sc2replay.Report) error {
+	for i := range reports {
+		report := &reports[i]
+
+		list, err := buildMechanicsList(replay, mech, report.PlayerID)
+		if err != nil {
+			return err
+		}
+
+		name := "Mechanics"
+		if len(reports) > 1 {
+			name = fmt.Sprintf("Mechanics of %v", report.PlayerName)
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   name,
+			Value:  list,
+			Inline: len(reports) > 1,
+		})
+	}
+	appendGuessedMechanicsFooter(embed, mech)
+
+	return nil
+}
+
+// List the mechanics of the player's race. Players whose race is not known
+// get all of them.
+func buildMechanicsList(replay *sc2replay.Replay, mech *sc2replay.Mechanics, playerID int64) (string, error) {
+	player, ok := mech.Players[playerID]
+	if !ok {
+		return "No mechanics", nil
+	}
+
+	sections := make([]string, 0, 3)
+	race := player.Race
+	known := race == "Zerg" || race == "Protoss" || race == "Terran"
+
+	if race == "Zerg" || !known {
+		section, err := buildInjectList(replay, mech, player)
+		if err != nil {
+			return "", err
+		}
+		sections = append(sections, section)
+	}
+	if race == "Protoss" || !known {
+		section, err := buildChronoboostList(replay, mech, player)
+		if err != nil {
+			return "", err
+		}
+		sections = append(sections, section)
+	}
+	if race == "Terran" || !known {
+		section, err := buildOrbitalList(replay, mech, player)
+		if err != nil {
+			return "", err
+		}
+		sections = append(sections, section)
+	}
+
+	return truncate(strings.Join(sections, "\n"), embedFieldLimit), nil
+}
+
+func buildInjectList(replay *sc2replay.Replay, mech *sc2replay.Mechanics, player *sc2replay.PlayerMechanics) (string, error) {
+	if !mech.Supports(units.SpawnLarva) {
+		return "Injects: not recognized in this replay\n", nil
+	}
+
+	out := strings.Builder{}
+	total := 0
+	for _, townhall := range player.Townhalls {
+		total += townhall.Injects
+	}
+	fmt.Fprintf(&out, "%v: %d\n", mechanicsLabel(mech, units.SpawnLarva, "Injects"), total)
+
+	for _, townhall := range player.Townhalls {
+		first, err := replay.DurationAt(townhall.FirstInject)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(
+			&out,
+			"- %v, first injected %v: %d injects, %.0f%% uptime\n",
+			townhall.Name,
+			formatTimestamp(first),
+			townhall.Injects,
+			townhall.Uptime()*100,
+		)
+	}
+
+	return out.String(), nil
+}
+
+func buildChronoboostList(replay *sc2replay.Replay, mech *sc2replay.Mechanics, player *sc2replay.PlayerMechanics) (string, error) {
+	if !mech.Supports(units.ChronoBoost) {
+		return "Chronoboosts: not recognized in this replay\n", nil
+	}
+
+	out := strings.Builder{}
+	fmt.Fprintf(&out, "%v: %d\n", mechanicsLabel(mech, units.ChronoBoost, "Chronoboosts"), player.Chronoboosts)
+
+	for _, nexus := range player.Nexuses {
+		first, err := replay.DurationAt(nexus.FirstChronoboost)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(
+			&out,
+			"- Nexus, first chronoboost %v: %d chronoboosts (%v)\n",
+			formatTimestamp(first),
+			nexus.Chronoboosts,
+			formatChronoboostTargets(nexus.Targets),
+		)
+	}
+
+	return out.String(), nil
+}
+
+// List the targets of chronoboosts, most frequent first.
+func formatChronoboostTargets(targets map[string]int) string {
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if targets[names[i]] != targets[names[j]] {
+			return targets[names[i]] > targets[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	formatted := make([]string, 0, len(names))
+	for _, name := range names {
+		formatted = append(formatted, fmt.Sprintf("%v %d", name, targets[name]))
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+func buildOrbitalList(replay *sc2replay.Replay, mech *sc2replay.Mechanics, player *sc2replay.PlayerMechanics) (string, error) {
+	out := strings.Builder{}
+	fmt.Fprintf(&out, "MULEs: %d\n", player.MULEs)
+	scans := mech.Supports(units.ScannerSweep)
+	if scans {
+		fmt.Fprintf(&out, "%v: %d\n", mechanicsLabel(mech, units.ScannerSweep, "Scans"), player.Scans)
+	} else {
+		out.WriteString("Scans: not recognized in this replay\n")
+	}
+
+	for _, orbital := range player.Orbitals {
+		since, err := replay.DurationAt(orbital.Since)
+		if err != nil {
+			return "", err
+		}
+
+		if scans {
+			fmt.Fprintf(&out, "- Orbital Command from %v: %d MULEs, %d scans\n", formatTimestamp(since), orbital.MULEs, orbital.Scans)
+		} else {
+			fmt.Fprintf(&out, "- Orbital Command from %v: %d MULEs\n", formatTimestamp(since), orbital.MULEs)
+		}
+	}
+
+	return out.String(), nil
+}
+
+// Label of a mechanic, marked as guessed if its ability was guessed by how it
+// was used.
+func mechanicsLabel(mech *sc2replay.Mechanics, ability string, label string) string {
+	if mech.Guessed(ability) {
+		return label + " (guessed)"
+	}
+
+	return label
+}
+
+// Explain guessed abilities in the embed's footer, if there are any.
+func appendGuessedMechanicsFooter(embed *discordgo.MessageEmbed, mech *sc2replay.Mechanics) {
+	for _, ability := range []string{units.SpawnLarva, units.ChronoBoost, units.ScannerSweep} {
+		if mech.Supports(ability) && mech.Guessed(ability) {
+			appendFooter(embed, "Abilities marked as guessed are unknown for this game version, and were recognized by how they were used, which might be wrong.")
+			return
+		}
+	}
+}
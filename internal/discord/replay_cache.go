@@ -0,0 +1,123 @@
+package discord
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"io"
+	"log"
+	"os"
+)
+
+// Like `withAttachedReplays`, but caches results by the content hash of the
+// replay, so repeated requests need neither download nor parse it again.
+//
+// `result` must be a pointer, which `analyse` fills in, and `respond` then
+// presents. Results are cached under the given key, which must therefore
+// cover all arguments affecting the result. Errors returned by `analyse` are
+// reported to the user verbatim.
+func (bot *Bot) withCachedAnalysis(ctxt CommandContext, key string, result interface{}, analyse func(replay *sc2replay.Replay) error, respond func()) {
+	attachments := ctxt.Msg().Attachments
+	if len(attachments) == 0 {
+		ctxt.Respond("Replay must be attached to message")
+		return
+	}
+
+	for _, att := range attachments {
+		cacheable := bot.cache != nil && att.Size <= bot.Config.ReplayCache.MaxReplaySize
+
+		// Attachment might have been posted previously
+		if cacheable && bot.loadCachedResult(bot.cachedHashOf(att), key, result) {
+			respond()
+			continue
+		}
+
+		file, err := downloadFile(att.URL)
+		if err != nil {
+			ctxt.InternalError(err)
+			return
+		}
+		defer os.Remove(file)
+
+		hash := ""
+		if cacheable {
+			hash, err = fileHash(file)
+			if err != nil {
+				log.Print(err)
+			} else if err := bot.cache.SetHash(att.URL, hash); err != nil {
+				log.Print(err)
+			}
+
+			// Same replay might have been posted by someone else
+			if bot.loadCachedResult(hash, key, result) {
+				respond()
+				continue
+			}
+		}
+
+		replay, err := sc2replay.FromFile(file)
+		if err != nil {
+			ctxt.Respond(fmt.Sprintf("Unable to load replay: %v", err))
+			return
+		}
+		defer replay.Close()
+
+		if err := analyse(&replay); err != nil {
+			ctxt.Respond(err.Error())
+			return
+		}
+
+		if len(hash) > 0 {
+			if err := bot.cache.Set(hash, key, result); err != nil {
+				log.Printf("Unable to cache result of replay %v: %v", hash, err)
+			}
+		}
+
+		respond()
+	}
+}
+
+// Return the hash of a previously seen attachment, or an empty string if it
+// is not known.
+func (bot *Bot) cachedHashOf(att *discordgo.MessageAttachment) string {
+	hash, ok, err := bot.cache.HashOf(att.URL)
+	if err != nil {
+		log.Print(err)
+	}
+	if !ok {
+		return ""
+	}
+
+	return hash
+}
+
+func (bot *Bot) loadCachedResult(hash string, key string, result interface{}) bool {
+	if len(hash) == 0 {
+		return false
+	}
+
+	ok, err := bot.cache.Get(hash, key, result)
+	if err != nil {
+		log.Print(err)
+	}
+
+	return ok
+}
+
+// Return the hex-encoded SHA-256 of the file's content.
+func fileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("Unable to open file: %v", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("Unable to hash file: %v", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -6,24 +6,261 @@ import (
 	"github.com/dragaera/probius/internal/sc2replay"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 func (bot *Bot) cmdSupply(ctxt CommandContext) bool {
-	ts := ctxt.Args()[0]
-	seconds, err := timestampToSeconds(ts)
+	args, options := splitOptions(ctxt.Args())
+	if len(args) < 1 {
+		return false
+	}
+
+	// The last argument is a player selector, unless it's a timestamp as
+	// well.
+	selector := ""
+	if len(args) > 1 {
+		if _, err := timestampToSeconds(args[len(args)-1]); err != nil {
+			selector = args[len(args)-1]
+			args = args[:len(args)-1]
+		}
+	}
+
+	timestamps := make([]int, 0, len(args))
+	for _, ts := range args {
+		seconds, err := timestampToSeconds(ts)
+		if err != nil {
+			ctxt.Respond(err.Error())
+			return true
+		}
+		timestamps = append(timestamps, seconds)
+	}
+	sort.Ints(timestamps)
+
+	timestampKeys := make([]string, 0, len(timestamps))
+	for _, seconds := range timestamps {
+		timestampKeys = append(timestampKeys, strconv.Itoa(seconds))
+	}
+	key := fmt.Sprintf(
+		"supply:%v:%v:%v:%v:%v",
+		strings.Join(timestampKeys, ","),
+		strings.ToLower(selector),
+		options["blocks"],
+		options["diagnostics"],
+		options["mechanics"],
+	)
+
+	result := supplyResult{}
+	bot.withCachedAnalysis(
+		ctxt,
+		key,
+		&result,
+		func(replay *sc2replay.Replay) error {
+			var err error
+			result, err = analyseSupply(replay, timestamps, selector, options)
+			return err
+		},
+		func() {
+			bot.recordCritterKills(ctxt, result.GameID, result.OwnerCritterKills)
+			bot.recordUnknownNames(result.GameID, result.BaseBuild, result.Unknown)
+			for i := range result.Embeds {
+				ctxt.RespondEmbed(&result.Embeds[i])
+			}
+		},
+	)
+
+	return true
+}
+
+// Result of `!supply`, as cached.
+type supplyResult struct {
+	Embeds    []discordgo.MessageEmbed
+	GameID    string
+	BaseBuild int64
+	// Critters killed by the replay's owner
+	OwnerCritterKills []sc2replay.CritterKill
+	// Units and upgrades missing from the unit catalog
+	Unknown unknownNames
+}
+
+func analyseSupply(replay *sc2replay.Replay, timestamps []int, selector string, options map[string]bool) (supplyResult, error) {
+	result := supplyResult{
+		GameID:    replay.GameID(),
+		BaseBuild: replay.Rep.Header.BaseBuild(),
+	}
+
+	playerIDs, err := selectPlayers(replay, selector)
 	if err != nil {
-		ctxt.Respond(err.Error())
-		return true
+		return result, err
 	}
 
+	// Reports of each player at each of the timestamps, as well as each
+	// player's report at the last timestamp.
+	reports, err := generateReports(replay, timestamps, playerIDs)
+	if err != nil {
+		return result, fmt.Errorf("Error while processing replay: %v", err)
+	}
+	latest := make([]sc2replay.Report, 0, len(playerIDs))
+	for _, playerReports := range reports {
+		latest = append(latest, playerReports[len(playerReports)-1])
+	}
+
+	result.OwnerCritterKills = ownerCritterKills(replay, latest)
+
+	all := make([]sc2replay.Report, 0, len(playerIDs)*len(timestamps))
+	for _, playerReports := range reports {
+		all = append(all, playerReports...)
+	}
+	result.Unknown = collectUnknownNames(all)
+
+	// Supply blocks and mechanics are of the last timestamp, which all
+	// players' latest reports share, so they are calculated only once.
+	var blocks *sc2replay.SupplyBlocks
+	if options["blocks"] {
+		blocks = &sc2replay.SupplyBlocks{Replay: replay}
+		if err := blocks.Generate(); err != nil {
+			return result, fmt.Errorf("Error while processing replay: %v", err)
+		}
+	}
+	var mech *sc2replay.Mechanics
+	if options["mechanics"] {
+		mech = &sc2replay.Mechanics{Replay: replay}
+		if err := mech.Calculate(latest[0].Ticks); err != nil {
+			return result, fmt.Errorf("Error while processing replay: %v", err)
+		}
+	}
+
+	if len(timestamps) > 1 {
+		for i := range reports {
+			embed := buildSupplyProgressionEmbed(reports[i])
+			addUnknownNamesFooter(&embed, collectUnknownNames(reports[i]))
+			addDiagnostics(&embed, replay, reports[i], options["diagnostics"])
+			if blocks != nil {
+				if err := addSupplyBlockFields(&embed, replay, blocks, latest[i:i+1]); err != nil {
+					return result, fmt.Errorf("Error while processing replay: %v", err)
+				}
+			}
+			if mech != nil {
+				if err := addMechanicsFields(&embed, replay, mech, latest[i:i+1]); err != nil {
+					return result, fmt.Errorf("Error while processing replay: %v", err)
+				}
+			}
+			result.Embeds = append(result.Embeds, embed)
+		}
+	} else {
+		ts := formatTimestamp(time.Duration(timestamps[0]) * time.Second)
+
+		var embed discordgo.MessageEmbed
+		if len(latest) == 1 {
+			embed = buildSupplyEmbed(&latest[0], ts)
+		} else {
+			embed = buildMultiSupplyEmbed(latest, ts)
+		}
+		addUnknownNamesFooter(&embed, collectUnknownNames(latest))
+		addDiagnostics(&embed, replay, latest, options["diagnostics"])
+
+		if blocks != nil {
+			if err := addSupplyBlockFields(&embed, replay, blocks, latest); err != nil {
+				return result, fmt.Errorf("Error while processing replay: %v", err)
+			}
+		}
+		if mech != nil {
+			if err := addMechanicsFields(&embed, replay, mech, latest); err != nil {
+				return result, fmt.Errorf("Error while processing replay: %v", err)
+			}
+		}
+		result.Embeds = append(result.Embeds, embed)
+	}
+
+	return result, nil
+}
+
+// Warn about problems encountered while processing the replay, listing them
+// in detail if requested.
+func addDiagnostics(embed *discordgo.MessageEmbed, replay *sc2replay.Replay, reports []sc2replay.Report, detailed bool) {
+	diags := collectDiagnostics(replay, reports)
+
+	if !detailed {
+		addDiagnosticsFooter(embed, diags)
+		return
+	}
+
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:   "Diagnostics",
+		Value:  buildDiagnosticsList(replay, diags),
+		Inline: false,
+	})
+}
+
+// Split arguments into positional ones, and options of the form `--name`.
+func splitOptions(args []string) ([]string, map[string]bool) {
+	positional := make([]string, 0, len(args))
+	options := make(map[string]bool)
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--") {
+			options[strings.ToLower(strings.TrimPrefix(arg, "--"))] = true
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	return positional, options
+}
+
+// Add a field listing supply blocks up until the report's timestamp, for
+// each report.
+func addSupplyBlockFields(embed *discordgo.MessageEmbed, replay *sc2replay.Replay, blocks *sc2replay.SupplyBlocks, reports []sc2replay.Report) error {
+	for i := range reports {
+		report := &reports[i]
+
+		list, err := buildSupplyBlockList(replay, blocks.Until(report.PlayerID, report.Ticks))
+		if err != nil {
+			return err
+		}
+
+		name := "Supply blocks"
+		if len(reports) > 1 {
+			name = fmt.Sprintf("Supply blocks of %v", report.PlayerName)
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   name,
+			Value:  list,
+			Inline: len(reports) > 1,
+		})
+	}
+
+	return nil
+}
+
+// Determine the IDs of the players a command applies to. Without a selector,
+// this is the replay's owner.
+func selectPlayers(replay *sc2replay.Replay, selector string) ([]int64, error) {
+	if len(selector) == 0 {
+		ownerID, err := replay.OwnerPlayerID()
+		if err != nil {
+			return nil, fmt.Errorf("Unable to determine replay owner, please specify a player: %v", err)
+		}
+
+		return []int64{ownerID}, nil
+	}
+
+	return replay.SelectPlayers(selector)
+}
+
+// Download and open every replay attached to the command's message, and call
+// `f` with each of them. Errors are reported to the user.
+func withAttachedReplays(ctxt CommandContext, f func(replay *sc2replay.Replay)) {
 	attachments := ctxt.Msg().Attachments
 	if len(attachments) == 0 {
 		ctxt.Respond("Replay must be attached to message")
-		return true
+		return
 	}
 
 	// I have not managed to have more than one attachment per message with
@@ -33,21 +270,19 @@ func (bot *Bot) cmdSupply(ctxt CommandContext) bool {
 		file, err := downloadFile(att.URL)
 		if err != nil {
 			ctxt.InternalError(err)
-			return true
+			return
 		}
 		defer os.Remove(file)
 
-		report, err := generateReport(file, seconds)
+		replay, err := sc2replay.FromFile(file)
 		if err != nil {
-			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
-			return true
+			ctxt.Respond(fmt.Sprintf("Unable to load replay: %v", err))
+			return
 		}
+		defer replay.Close()
 
-		embed := buildSupplyEmbed(&report, ts)
-		ctxt.RespondEmbed(&embed)
+		f(&replay)
 	}
-
-	return true
 }
 
 func timestampToSeconds(timestamp string) (int, error) {
@@ -87,6 +322,17 @@ func timestampToSeconds(timestamp string) (int, error) {
 	return hours*3600 + minutes*60 + seconds, nil
 }
 
+// Format a duration as [HH:]MM:SS, the inverse of `timestampToSeconds`.
+func formatTimestamp(d time.Duration) string {
+	seconds := int(d.Seconds())
+
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, (seconds%3600)/60, seconds%60)
+	}
+
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
 func downloadFile(URL string) (string, error) {
 	response, err := http.Get(URL)
 	if err != nil {
@@ -110,37 +356,24 @@ func downloadFile(URL string) (string, error) {
 	return file.Name(), nil
 }
 
-func generateReport(file string, duration int) (sc2replay.Report, error) {
-	var report = sc2replay.Report{}
-
-	replay, err := sc2replay.FromFile(file)
-	if err != nil {
-		return report, fmt.Errorf("Unable to load replay: %v\n", err)
-	}
-	defer replay.Close()
-
-	ticks, err := replay.TicksUntilSeconds(float64(duration))
-	if err != nil {
-		return report, fmt.Errorf("Unable to determine amount of ticks until 7:00: %v\n", err)
-	}
-
-	ownerID, err := replay.OwnerPlayerID()
-	if err != nil {
-		return report, fmt.Errorf("Unable to determine owner player ID: %v", err)
-	}
-
-	report = sc2replay.Report{
-		PlayerID: ownerID,
-		Replay:   &replay,
+// Generate reports of each of the given players at each of the given durations
+// (in seconds, ascending), processing the replay only once.
+func generateReports(replay *sc2replay.Replay, durations []int, playerIDs []int64) ([][]sc2replay.Report, error) {
+	ticks := make([]int64, 0, len(durations))
+	for _, duration := range durations {
+		t, err := replay.TicksUntilSeconds(float64(duration))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to determine amount of ticks until %d seconds: %v", duration, err)
+		}
+		ticks = append(ticks, t)
 	}
-	report.At(ticks)
 
-	return report, nil
+	return sc2replay.ReportsAt(replay, playerIDs, ticks), nil
 }
 
 func buildSupplyEmbed(report *sc2replay.Report, timestamp string) discordgo.MessageEmbed {
 	ownerField := discordgo.MessageEmbedField{
-		Name:   "Replay owner",
+		Name:   "Player",
 		Value:  report.PlayerName,
 		Inline: true,
 	}
@@ -153,7 +386,19 @@ func buildSupplyEmbed(report *sc2replay.Report, timestamp string) discordgo.Mess
 
 	supplyField := discordgo.MessageEmbedField{
 		Name:   "Supply",
-		Value:  strconv.Itoa(report.IngameSupply()),
+		Value:  fmt.Sprintf("%v, army worth %v", formatSupply(report), report.ArmyValue),
+		Inline: true,
+	}
+
+	investmentField := discordgo.MessageEmbedField{
+		Name:   "Tech / Economy",
+		Value:  fmt.Sprintf("%v / %v", report.TechValue, report.EconomyValue),
+		Inline: true,
+	}
+
+	apmField := discordgo.MessageEmbedField{
+		Name:   "APM / EPM",
+		Value:  fmt.Sprintf("%.0f / %.0f", report.APM, report.EPM),
 		Inline: true,
 	}
 
@@ -181,14 +426,39 @@ func buildSupplyEmbed(report *sc2replay.Report, timestamp string) discordgo.Mess
 		Inline: true,
 	}
 
+	productionField := discordgo.MessageEmbedField{
+		Name:   "In production",
+		Value:  buildProductionList(report),
+		Inline: true,
+	}
+
+	morphingField := discordgo.MessageEmbedField{
+		Name:   "In eggs and cocoons",
+		Value:  buildMorphingList(report),
+		Inline: true,
+	}
+
 	fields := []*discordgo.MessageEmbedField{
 		&ownerField,
 		&timestampField,
 		&supplyField,
+		&investmentField,
+		&apmField,
 		&critterField,
 		&unitField,
 		&buildingField,
 		&upgradeField,
+		&productionField,
+	}
+	if len(report.Morphing) > 0 {
+		fields = append(fields, &morphingField)
+	}
+	if len(report.OwnershipTransfers) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "Units changing hands",
+			Value:  buildOwnershipTransferList(report),
+			Inline: false,
+		})
 	}
 
 	embed := discordgo.MessageEmbed{
@@ -199,6 +469,145 @@ func buildSupplyEmbed(report *sc2replay.Report, timestamp string) discordgo.Mess
 	return embed
 }
 
+// Build an embed showing the reports of multiple players side by side.
+func buildMultiSupplyEmbed(reports []sc2replay.Report, timestamp string) discordgo.MessageEmbed {
+	fields := make([]*discordgo.MessageEmbedField, 0, len(reports))
+
+	for i := range reports {
+		report := &reports[i]
+		out := strings.Builder{}
+
+		fmt.Fprintf(&out, "**Supply**: %v, army worth %v\n", formatSupply(report), report.ArmyValue)
+		fmt.Fprintf(&out, "**Tech / Economy**: %v / %v\n", report.TechValue, report.EconomyValue)
+		fmt.Fprintf(&out, "**APM / EPM**: %.0f / %.0f\n", report.APM, report.EPM)
+		fmt.Fprintf(&out, "**Units**\n%v", buildUnitList(report))
+		fmt.Fprintf(&out, "**Buildings**\n%v", buildBuildingList(report))
+		fmt.Fprintf(&out, "**Upgrades**\n%v", buildUpgradeList(report))
+		fmt.Fprintf(&out, "**In production**\n%v", buildProductionList(report))
+		if len(report.Morphing) > 0 {
+			fmt.Fprintf(&out, "**In eggs and cocoons**\n%v", buildMorphingList(report))
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   report.PlayerName,
+			Value:  truncate(out.String(), embedFieldLimit),
+			Inline: true,
+		})
+	}
+
+	embed := discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("Supply report at %v", timestamp),
+		Fields: fields,
+	}
+
+	return embed
+}
+
+// Build an embed showing how a player's supply, units, buildings and
+// upgrades changed across multiple reports, ordered by time.
+func buildSupplyProgressionEmbed(reports []sc2replay.Report) discordgo.MessageEmbed {
+	fields := make([]*discordgo.MessageEmbedField, 0, len(reports))
+
+	var previous *sc2replay.Report
+	for i := range reports {
+		report := &reports[i]
+		out := strings.Builder{}
+
+		supplyDelta := 0
+		previousUnits := map[string]int{}
+		previousBuildings := map[string]int{}
+		previousUpgrades := map[string]bool{}
+		if previous != nil {
+			supplyDelta = report.IngameSupply() - previous.IngameSupply()
+			previousUnits = previous.UnitCount
+			previousBuildings = previous.BuildingCount
+			for _, upgrade := range previous.Upgrades {
+				previousUpgrades[upgrade.Name] = true
+			}
+		}
+
+		fmt.Fprintf(&out, "**Supply**: %d (%+d), army worth %v\n", report.IngameSupply(), supplyDelta, report.ArmyValue)
+		fmt.Fprintf(&out, "**APM / EPM**: %.0f / %.0f\n", report.APM, report.EPM)
+		fmt.Fprintf(&out, "**Units**\n%v", buildCountChangeList(report.UnitCount, previousUnits))
+		fmt.Fprintf(&out, "**Buildings**\n%v", buildCountChangeList(report.BuildingCount, previousBuildings))
+		fmt.Fprint(&out, "**Upgrades**\n")
+		for _, upgrade := range report.Upgrades {
+			if !previousUpgrades[upgrade.Name] {
+				fmt.Fprintf(&out, "- %v\n", upgrade.Name)
+			}
+		}
+		fmt.Fprintf(&out, "**In production**\n%v", buildProductionList(report))
+
+		name := fmt.Sprintf("Ticks %d", report.Ticks)
+		if ts, err := report.Replay.DurationAt(report.Ticks); err == nil {
+			name = formatTimestamp(ts)
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   name,
+			Value:  truncate(out.String(), embedFieldLimit),
+			Inline: true,
+		})
+
+		previous = report
+	}
+
+	title := "Supply report"
+	if len(reports) > 0 {
+		title = fmt.Sprintf("Supply report of %v", reports[0].PlayerName)
+	}
+
+	embed := discordgo.MessageEmbed{
+		Title:  title,
+		Fields: fields,
+	}
+
+	return embed
+}
+
+// List counts by name, along with the change compared to the previous counts.
+// Names which are no longer present are listed with a count of zero.
+func buildCountChangeList(current map[string]int, previous map[string]int) string {
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	out := strings.Builder{}
+	for _, name := range names {
+		delta := current[name] - previous[name]
+		if delta == 0 {
+			fmt.Fprintf(&out, "- %v: %d\n", name, current[name])
+		} else {
+			fmt.Fprintf(&out, "- %v: %d (%+d)\n", name, current[name], delta)
+		}
+	}
+
+	return out.String()
+}
+
+// Truncate a string to at most `limit` bytes, indicating that it was cut
+// short. Multi-byte characters, eg in player names, are never split.
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+
+	const ellipsis = "\n..."
+	cut := limit - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut -= 1
+	}
+
+	return s[:cut] + ellipsis
+}
+
 func buildCritterList(report *sc2replay.Report) string {
 	out := strings.Builder{}
 
@@ -219,6 +628,83 @@ func buildCritterList(report *sc2replay.Report) string {
 		)
 	}
 
+	for _, kill := range report.CritterKills {
+		fmt.Fprintf(&out, "%v\n", describeCritterKill(report.Replay, kill))
+	}
+
+	return truncate(out.String(), embedFieldLimit)
+}
+
+func describeCritterKill(replay *sc2replay.Replay, kill sc2replay.CritterKill) string {
+	out := strings.Builder{}
+
+	ts, err := replay.DurationAt(kill.Loop)
+	if err == nil {
+		fmt.Fprintf(&out, "`%v` ", formatTimestamp(ts))
+	}
+	fmt.Fprintf(&out, "%v killed", kill.Critter.Name)
+
+	if killer, err := replay.PlayerName(kill.KillerPlayerID); err == nil {
+		fmt.Fprintf(&out, " by %v", killer)
+		if len(kill.KillerUnit) > 0 {
+			fmt.Fprintf(&out, "'s %v", kill.KillerUnit)
+		}
+	}
+
+	return out.String()
+}
+
+func buildOwnershipTransferList(report *sc2replay.Report) string {
+	out := strings.Builder{}
+
+	for _, transfer := range report.OwnershipTransfers {
+		fmt.Fprintf(&out, "%v\n", describeOwnershipTransfer(report.Replay, transfer))
+	}
+
+	return truncate(out.String(), embedFieldLimit)
+}
+
+func describeOwnershipTransfer(replay *sc2replay.Replay, transfer sc2replay.OwnershipTransfer) string {
+	out := strings.Builder{}
+
+	ts, err := replay.DurationAt(transfer.Loop)
+	if err == nil {
+		fmt.Fprintf(&out, "`%v` ", formatTimestamp(ts))
+	}
+
+	name := transfer.Unit
+	catalog := replay.Catalog()
+	if unit, ok := catalog.Units[name]; ok {
+		name = unit.Name
+	} else if building, ok := catalog.Buildings[name]; ok {
+		name = building.Name
+	}
+
+	playerName := func(playerID int64) string {
+		if name, err := replay.PlayerName(playerID); err == nil {
+			return name
+		}
+		return fmt.Sprintf("player %d", playerID)
+	}
+
+	if transfer.FromOwnerID == transfer.ToOwnerID {
+		fmt.Fprintf(
+			&out,
+			"%v of %v controlled by %v",
+			name,
+			playerName(transfer.ToOwnerID),
+			playerName(transfer.ToControllerID),
+		)
+	} else {
+		fmt.Fprintf(
+			&out,
+			"%v given from %v to %v",
+			name,
+			playerName(transfer.FromOwnerID),
+			playerName(transfer.ToOwnerID),
+		)
+	}
+
 	return out.String()
 }
 
@@ -252,6 +738,55 @@ func buildBuildingList(report *sc2replay.Report) string {
 	return out.String()
 }
 
+// Format the supply as shown in-game, along with the supply of completed
+// units if it differs.
+func formatSupply(report *sc2replay.Report) string {
+	completed := int(math.Round(report.CompletedSupply))
+	if completed == report.IngameSupply() {
+		return fmt.Sprintf("%d", report.IngameSupply())
+	}
+
+	return fmt.Sprintf("%d (%d completed)", report.IngameSupply(), completed)
+}
+
+// List units and buildings in production, along with their progress.
+func buildProductionList(report *sc2replay.Report) string {
+	out := strings.Builder{}
+
+	productions := append(
+		append([]sc2replay.Production{}, report.UnitsInProduction...),
+		report.BuildingsInProduction...,
+	)
+	for _, prod := range productions {
+		fmt.Fprintf(&out, "- %v (%.0f%%)\n", prod.Name, prod.Progress*100)
+	}
+
+	if out.Len() == 0 {
+		out.WriteString("Nothing\n")
+	}
+
+	return truncate(out.String(), embedFieldLimit)
+}
+
+// List units in eggs and cocoons, along with their progress.
+func buildMorphingList(report *sc2replay.Report) string {
+	out := strings.Builder{}
+
+	for _, prod := range report.Morphing {
+		if prod.Count > 1 {
+			fmt.Fprintf(&out, "- %dx %v (%.0f%%)\n", prod.Count, prod.Name, prod.Progress*100)
+		} else {
+			fmt.Fprintf(&out, "- %v (%.0f%%)\n", prod.Name, prod.Progress*100)
+		}
+	}
+
+	if out.Len() == 0 {
+		out.WriteString("Nothing\n")
+	}
+
+	return truncate(out.String(), embedFieldLimit)
+}
+
 func buildUpgradeList(report *sc2replay.Report) string {
 	out := strings.Builder{}
 
@@ -0,0 +1,98 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"strings"
+)
+
+func (bot *Bot) cmdBlocks(ctxt CommandContext) bool {
+	selector := "all"
+	if len(ctxt.Args()) > 0 {
+		selector = ctxt.Args()[0]
+	}
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		playerIDs, err := selectPlayers(replay, selector)
+		if err != nil {
+			ctxt.Respond(err.Error())
+			return
+		}
+
+		blocks := sc2replay.SupplyBlocks{Replay: replay}
+		if err := blocks.Generate(); err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		fields := make([]*discordgo.MessageEmbedField, 0, len(playerIDs))
+		for _, playerID := range playerIDs {
+			name, err := replay.PlayerName(playerID)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			list, err := buildSupplyBlockList(replay, blocks.Blocks[playerID])
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:   name,
+				Value:  list,
+				Inline: true,
+			})
+		}
+
+		embed := discordgo.MessageEmbed{
+			Title:  "Supply blocks",
+			Fields: fields,
+		}
+		ctxt.RespondEmbed(&embed)
+	})
+
+	return true
+}
+
+func buildSupplyBlockList(replay *sc2replay.Replay, blocks []sc2replay.SupplyBlock) (string, error) {
+	out := strings.Builder{}
+	lines := strings.Builder{}
+	total := int64(0)
+
+	for _, block := range blocks {
+		total += block.Duration()
+
+		start, err := replay.DurationAt(block.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := replay.DurationAt(block.End)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(
+			&lines,
+			"- %v - %v (%.0fs)\n",
+			formatTimestamp(start),
+			formatTimestamp(end),
+			(end - start).Seconds(),
+		)
+	}
+
+	if len(blocks) == 0 {
+		return "Never supply blocked :)", nil
+	}
+
+	totalDuration, err := replay.DurationAt(total)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&out, "**Total**: %.0fs in %d blocks\n", totalDuration.Seconds(), len(blocks))
+	out.WriteString(lines.String())
+
+	return truncate(out.String(), embedFieldLimit), nil
+}
@@ -0,0 +1,44 @@
+package discord
+
+import "testing"
+
+func TestTimestampToSeconds(t *testing.T) {
+	tests := []struct {
+		timestamp string
+		want      int
+		wantErr   bool
+	}{
+		{timestamp: "00:00", want: 0},
+		{timestamp: "5:30", want: 330},
+		{timestamp: "05:30", want: 330},
+		{timestamp: "1:02:03", want: 3723},
+		{timestamp: "0:59:59", want: 3599},
+		{timestamp: "", wantErr: true},
+		{timestamp: "330", wantErr: true},
+		{timestamp: "1:2:3:4", wantErr: true},
+		{timestamp: "5:60", wantErr: true},
+		{timestamp: "60:00", wantErr: true},
+		{timestamp: "-1:00", wantErr: true},
+		{timestamp: "-1:00:00", wantErr: true},
+		{timestamp: "a:00", wantErr: true},
+		{timestamp: "5:", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.timestamp, func(t *testing.T) {
+			got, err := timestampToSeconds(test.timestamp)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("timestampToSeconds(%q) = %d, want error", test.timestamp, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("timestampToSeconds(%q) failed: %v", test.timestamp, err)
+			}
+			if got != test.want {
+				t.Errorf("timestampToSeconds(%q) = %d, want %d", test.timestamp, got, test.want)
+			}
+		})
+	}
+}
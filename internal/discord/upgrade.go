@@ -0,0 +1,93 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dragaera/probius/internal/sc2replay"
+	"strings"
+)
+
+func (bot *Bot) cmdUpgrades(ctxt CommandContext) bool {
+	selector := "all"
+	if len(ctxt.Args()) > 0 {
+		selector = ctxt.Args()[0]
+	}
+
+	withAttachedReplays(ctxt, func(replay *sc2replay.Replay) {
+		playerIDs, err := selectPlayers(replay, selector)
+		if err != nil {
+			ctxt.Respond(err.Error())
+			return
+		}
+
+		researches := sc2replay.UpgradeResearches{Replay: replay}
+		if err := researches.Generate(); err != nil {
+			ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+			return
+		}
+
+		fields := make([]*discordgo.MessageEmbedField, 0, len(playerIDs))
+		for _, playerID := range playerIDs {
+			name, err := replay.PlayerName(playerID)
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			list, err := buildUpgradeResearchList(replay, researches.Researches[playerID])
+			if err != nil {
+				ctxt.Respond(fmt.Sprintf("Error while processing replay: %v", err))
+				return
+			}
+
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:   name,
+				Value:  list,
+				Inline: true,
+			})
+		}
+
+		embed := discordgo.MessageEmbed{
+			Title:  "Upgrade timings",
+			Fields: fields,
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: "Starts marked with ~ are estimated from research time, and are earlier than the actual start if research was sped up.",
+			},
+		}
+		ctxt.RespondEmbed(&embed)
+	})
+
+	return true
+}
+
+func buildUpgradeResearchList(replay *sc2replay.Replay, researches []*sc2replay.UpgradeResearch) (string, error) {
+	out := strings.Builder{}
+
+	for _, research := range researches {
+		started, err := replay.DurationAt(research.Started)
+		if err != nil {
+			return "", err
+		}
+		finished, err := replay.DurationAt(research.Finished)
+		if err != nil {
+			return "", err
+		}
+
+		estimated := ""
+		if research.Estimated {
+			estimated = "~"
+		}
+
+		fmt.Fprintf(&out, "`%v%v`-`%v` %v", estimated, formatTimestamp(started), formatTimestamp(finished), research.Name)
+		if len(research.Producer) > 0 {
+			fmt.Fprintf(&out, " (%v)", research.Producer)
+		}
+		out.WriteString("\n")
+	}
+
+	if len(researches) == 0 {
+		return "No upgrades", nil
+	}
+
+	return truncate(out.String(), embedFieldLimit), nil
+}
@@ -0,0 +1,151 @@
+package persistence
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Reference build order which replays can be compared against. It belongs to
+// either a user or a guild, the other ID being nil.
+type Benchmark struct {
+	ID             uint          `gorm:"primaryKey"`
+	Name           string        `gorm:"not null;index"`
+	DiscordUserID  *uint         `gorm:"index"`
+	DiscordUser    *DiscordUser  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	DiscordGuildID *uint         `gorm:"index"`
+	DiscordGuild   *DiscordGuild `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Ordered by position
+	Steps     []BenchmarkStep `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type BenchmarkStep struct {
+	ID          uint `gorm:"primaryKey"`
+	BenchmarkID uint `gorm:"not null;index"`
+	// Position of the step within the benchmark, starting at zero
+	Position int `gorm:"not null"`
+	// Human-readable name of a unit, building or upgrade, as in the unit
+	// catalog
+	Name   string `gorm:"not null"`
+	Supply int    `gorm:"not null"`
+	// Target time, in seconds of game time
+	Seconds int64 `gorm:"not null"`
+}
+
+// Whether the benchmark belongs to a guild, rather than a user.
+func (benchmark *Benchmark) IsGuildBenchmark() bool {
+	return benchmark.DiscordGuildID != nil
+}
+
+// Restrict a query to benchmarks of the same owner as this one.
+func (benchmark *Benchmark) sameOwner(orm *gorm.DB) *gorm.DB {
+	if benchmark.IsGuildBenchmark() {
+		return orm.Where("discord_guild_id = ? AND discord_user_id IS NULL", *benchmark.DiscordGuildID)
+	}
+
+	return orm.Where("discord_user_id = ? AND discord_guild_id IS NULL", *benchmark.DiscordUserID)
+}
+
+// Store the benchmark, replacing the one of the same name and owner, if any.
+func (benchmark *Benchmark) Save(orm *gorm.DB) error {
+	for i := range benchmark.Steps {
+		benchmark.Steps[i].Position = i
+	}
+
+	err := orm.Transaction(func(tx *gorm.DB) error {
+		if err := deleteBenchmark(tx, benchmark); err != nil {
+			return err
+		}
+
+		return tx.Create(benchmark).Error
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to save benchmark: %v", err)
+	}
+
+	return nil
+}
+
+// Delete the benchmark of the same name and owner as this one. Returns false
+// if there is none.
+func (benchmark *Benchmark) Delete(orm *gorm.DB) (bool, error) {
+	existing := Benchmark{}
+	err := benchmark.sameOwner(orm).Where("name = ?", benchmark.Name).Take(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("Unable to retrieve benchmark: %v", err)
+	}
+
+	err = orm.Transaction(func(tx *gorm.DB) error {
+		return deleteBenchmark(tx, &existing)
+	})
+	if err != nil {
+		return false, fmt.Errorf("Unable to delete benchmark: %v", err)
+	}
+
+	return true, nil
+}
+
+// Delete the benchmark of the same name and owner as the given one,
+// including its steps, if it exists.
+func deleteBenchmark(tx *gorm.DB, benchmark *Benchmark) error {
+	ids := make([]uint, 0)
+	err := benchmark.sameOwner(tx.Model(&Benchmark{})).
+		Where("name = ?", benchmark.Name).
+		Pluck("id", &ids).
+		Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	if err := tx.Where("benchmark_id IN ?", ids).Delete(&BenchmarkStep{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("id IN ?", ids).Delete(&Benchmark{}).Error
+}
+
+// Find the benchmark with the given name, preferring the user's own over the
+// guild's.
+func FindBenchmark(orm *gorm.DB, name string, userID uint, guildID uint) (Benchmark, bool, error) {
+	benchmarks, err := loadBenchmarks(
+		orm.Where("name = ?", name),
+		userID,
+		guildID,
+	)
+	if err != nil || len(benchmarks) == 0 {
+		return Benchmark{}, false, err
+	}
+
+	return benchmarks[0], true, nil
+}
+
+// Return the benchmarks available to the user, their own ones first.
+func Benchmarks(orm *gorm.DB, userID uint, guildID uint) ([]Benchmark, error) {
+	return loadBenchmarks(orm, userID, guildID)
+}
+
+func loadBenchmarks(query *gorm.DB, userID uint, guildID uint) ([]Benchmark, error) {
+	benchmarks := make([]Benchmark, 0)
+
+	err := query.
+		Where(
+			"((discord_user_id = ? AND discord_guild_id IS NULL) OR (discord_guild_id = ? AND discord_user_id IS NULL))",
+			userID,
+			guildID,
+		).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).
+		Order("discord_user_id IS NULL, name").
+		Find(&benchmarks).
+		Error
+	if err != nil {
+		return benchmarks, fmt.Errorf("Unable to retrieve benchmarks: %v", err)
+	}
+
+	return benchmarks, nil
+}
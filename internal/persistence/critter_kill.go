@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+type CritterKill struct {
+	ID             uint         `gorm:"primaryKey"`
+	DiscordUserID  uint         `gorm:"not null;uniqueIndex:idx_critter_kill"`
+	DiscordUser    DiscordUser  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	DiscordGuildID uint         `gorm:"not null;uniqueIndex:idx_critter_kill"`
+	DiscordGuild   DiscordGuild `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Identifies the game, so posting the same replay twice does not count
+	// twice.
+	GameID string `gorm:"not null;uniqueIndex:idx_critter_kill"`
+	Loop   int64  `gorm:"not null;uniqueIndex:idx_critter_kill"`
+	// Unit tag of the critter, as multiple critters might die in the same
+	// loop.
+	UnitTag    int64 `gorm:"not null;uniqueIndex:idx_critter_kill"`
+	Critter    string
+	KillerUnit string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type CritterLeaderboardEntry struct {
+	DiscordUserID uint
+	Name          string
+	Kills         int
+}
+
+// Store the kill, unless it is known already.
+func (kill *CritterKill) Record(orm *gorm.DB) error {
+	err := orm.
+		Where(CritterKill{
+			DiscordUserID:  kill.DiscordUserID,
+			DiscordGuildID: kill.DiscordGuildID,
+			GameID:         kill.GameID,
+			Loop:           kill.Loop,
+			UnitTag:        kill.UnitTag,
+		}).
+		Attrs(CritterKill{
+			Critter:    kill.Critter,
+			KillerUnit: kill.KillerUnit,
+		}).
+		FirstOrCreate(kill).
+		Error
+	if err != nil {
+		return fmt.Errorf("Unable to record critter kill: %v", err)
+	}
+
+	return nil
+}
+
+// Return the users with the most critter kills in the given guild.
+func CritterLeaderboard(orm *gorm.DB, guildID uint, limit int) ([]CritterLeaderboardEntry, error) {
+	entries := make([]CritterLeaderboardEntry, 0)
+
+	err := orm.
+		Model(&CritterKill{}).
+		Select("critter_kills.discord_user_id, discord_users.name, count(*) as kills").
+		Joins("JOIN discord_users ON discord_users.id = critter_kills.discord_user_id").
+		Where("critter_kills.discord_guild_id = ?", guildID).
+		Group("critter_kills.discord_user_id, discord_users.name").
+		Order("kills DESC").
+		Limit(limit).
+		Scan(&entries).
+		Error
+	if err != nil {
+		return entries, fmt.Errorf("Unable to retrieve critter leaderboard: %v", err)
+	}
+
+	return entries, nil
+}
@@ -35,7 +35,7 @@ func InitializeORM(cfg config.DBConfig) (*gorm.DB, error) {
 		},
 	)
 	if err != nil {
-		return db, fmt.Errorf("Unable to connect to database:", err)
+		return db, fmt.Errorf("Unable to connect to database: %v", err)
 	}
 
 	return db, nil
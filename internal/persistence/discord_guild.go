@@ -7,6 +7,9 @@ import (
 	"time"
 )
 
+// Discord ID of the pseudo-guild which direct messages are associated with.
+const DMGuildDiscordID string = "0"
+
 type DiscordGuild struct {
 	ID        uint `gorm:"primaryKey"`
 	DiscordID string
@@ -20,7 +23,7 @@ func GetDMGuild(orm *gorm.DB) (DiscordGuild, error) {
 	guild := DiscordGuild{}
 
 	err := orm.
-		Where(DiscordGuild{DiscordID: "0"}).
+		Where(DiscordGuild{DiscordID: DMGuildDiscordID}).
 		Attrs(DiscordGuild{Name: "Direct Message", OwnerID: "0"}).
 		FirstOrCreate(&guild).
 		Error
@@ -0,0 +1,100 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dragaera/probius/internal/config"
+	"github.com/gomodule/redigo/redis"
+)
+
+const replayCachePrefix string = "probius:replay_cache"
+
+// Caches results of replay analyses in Redis, keyed by the SHA-256 of the
+// replay file.
+type ReplayCache struct {
+	Redis  *redis.Pool
+	Config config.ReplayCacheConfig
+	// Entries cached by other versions are ignored. Change this whenever
+	// analyses change in a way which affects their results.
+	Version string
+}
+
+// Return the hash of the replay which was downloaded from the given URL, if
+// known.
+func (cache *ReplayCache) HashOf(url string) (string, bool, error) {
+	conn := cache.Redis.Get()
+	defer conn.Close()
+
+	hash, err := redis.String(conn.Do("GET", cache.urlKey(url)))
+	if err == redis.ErrNil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("Unable to look up replay hash: %v", err)
+	}
+
+	return hash, true, nil
+}
+
+// Remember the hash of the replay downloaded from the given URL.
+func (cache *ReplayCache) SetHash(url string, hash string) error {
+	conn := cache.Redis.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("SET", cache.urlKey(url), hash, "EX", cache.Config.TTL); err != nil {
+		return fmt.Errorf("Unable to store replay hash: %v", err)
+	}
+
+	return nil
+}
+
+// Load the result cached under the given name for the replay with the given
+// hash into `v`. Returns false if there is none.
+func (cache *ReplayCache) Get(hash string, name string, v interface{}) (bool, error) {
+	conn := cache.Redis.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("GET", cache.resultKey(hash, name)))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Unable to look up cached result: %v", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("Unable to decode cached result: %v", err)
+	}
+
+	return true, nil
+}
+
+// Cache `v` under the given name for the replay with the given hash. Results
+// exceeding the configured size limit are skipped.
+func (cache *ReplayCache) Set(hash string, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("Unable to encode result: %v", err)
+	}
+
+	if len(data) > cache.Config.MaxEntrySize {
+		return fmt.Errorf("Result of %d bytes exceeds cache limit of %d bytes", len(data), cache.Config.MaxEntrySize)
+	}
+
+	conn := cache.Redis.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("SET", cache.resultKey(hash, name), data, "EX", cache.Config.TTL); err != nil {
+		return fmt.Errorf("Unable to store result: %v", err)
+	}
+
+	return nil
+}
+
+func (cache *ReplayCache) urlKey(url string) string {
+	return fmt.Sprintf("%v:url:%v", replayCachePrefix, url)
+}
+
+func (cache *ReplayCache) resultKey(hash string, name string) string {
+	return fmt.Sprintf("%v:%v:%v:%v", replayCachePrefix, cache.Version, hash, name)
+}
@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	UnknownCatalogUnit    string = "unit"
+	UnknownCatalogUpgrade string = "upgrade"
+)
+
+// A unit, building or upgrade encountered in a replay, which is missing from
+// the unit catalog.
+type UnknownCatalogName struct {
+	ID uint `gorm:"primaryKey"`
+	// One of `UnknownCatalogUnit` and `UnknownCatalogUpgrade`
+	Kind string `gorm:"not null;uniqueIndex:idx_unknown_catalog_name"`
+	Name string `gorm:"not null;uniqueIndex:idx_unknown_catalog_name"`
+	// Identifies the game, so posting the same replay twice does not count
+	// twice.
+	GameID    string `gorm:"not null;uniqueIndex:idx_unknown_catalog_name"`
+	BaseBuild int64  `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type UnknownCatalogNameSummary struct {
+	Kind string
+	Name string
+	// Number of distinct games the name was encountered in
+	Games int
+	// Range of base builds of those games
+	MinBuild int64
+	MaxBuild int64
+}
+
+// Store the name, unless it is known already for this game.
+func (name *UnknownCatalogName) Record(orm *gorm.DB) error {
+	err := orm.
+		Where(UnknownCatalogName{
+			Kind:   name.Kind,
+			Name:   name.Name,
+			GameID: name.GameID,
+		}).
+		Attrs(UnknownCatalogName{
+			BaseBuild: name.BaseBuild,
+		}).
+		FirstOrCreate(name).
+		Error
+	if err != nil {
+		return fmt.Errorf("Unable to record unknown catalog name: %v", err)
+	}
+
+	return nil
+}
+
+// Return unknown names aggregated across all games, most frequent ones first.
+func UnknownCatalogNames(orm *gorm.DB, limit int) ([]UnknownCatalogNameSummary, error) {
+	entries := make([]UnknownCatalogNameSummary, 0)
+
+	err := orm.
+		Model(&UnknownCatalogName{}).
+		Select("kind, name, count(distinct game_id) as games, min(base_build) as min_build, max(base_build) as max_build").
+		Group("kind, name").
+		Order("games DESC, kind, name").
+		Limit(limit).
+		Scan(&entries).
+		Error
+	if err != nil {
+		return entries, fmt.Errorf("Unable to retrieve unknown catalog names: %v", err)
+	}
+
+	return entries, nil
+}
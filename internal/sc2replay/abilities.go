@@ -0,0 +1,221 @@
+package sc2replay
+
+import (
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/dragaera/probius/internal/sc2replay/units"
+	"github.com/icza/s2prot"
+	"sort"
+)
+
+// Abilities are only recognized by how they were used if they were used at
+// least this often, and nearly always with the unit casting them selected, so
+// that eg queens ordered to move onto a hatchery are not mistaken for injects,
+// as moving is used by most other units as well.
+const (
+	minAbilityUses       = 2
+	minAbilityCasterRate = 0.9
+)
+
+// Ability used by race mechanics, as used in the commands of game events.
+type mechanicsAbility struct {
+	Abil events.CmdAbil
+	// Whether it was recognized by how it was used, rather than taken from
+	// the catalog.
+	Guessed bool
+}
+
+// Return the abilities used by race mechanics, by ingame name, determined on
+// first use. Chronoboost is always listed as `units.ChronoBoost`, no matter
+// the game version.
+func (replay *Replay) mechanicsAbilities() (map[string]mechanicsAbility, error) {
+	if replay.abilities == nil {
+		abilities, err := findMechanicsAbilities(replay)
+		if err != nil {
+			return nil, err
+		}
+		replay.abilities = abilities
+	}
+
+	return replay.abilities, nil
+}
+
+// Abilities are taken from the catalog where it knows them, which is only the
+// case for the base builds they were verified for. For other builds, they are
+// guessed by how they were used in the replay:
+//
+//   - SpawnLarva targets one of the player's townhalls, with a queen selected
+//   - ChronoBoost targets one of the player's buildings, with a nexus selected
+//   - CalldownMULE targets a unit of another player, ie a mineral field, with
+//     an orbital command selected
+//   - ScannerSweep targets a point with a landed orbital command selected
+//
+// Of each, the ability used most often is taken, provided it was nearly
+// always used with its caster selected.
+func findMechanicsAbilities(replay *Replay) (map[string]mechanicsAbility, error) {
+	abilities := make(map[string]mechanicsAbility)
+	catalog := replay.Catalog()
+	for _, name := range []string{units.SpawnLarva, units.ChronoBoost, units.ChronoBoostEnergyCost, units.CalldownMULE, units.ScannerSweep} {
+		ability := catalog.Abilities[name]
+		if !ability.Known() {
+			continue
+		}
+
+		if name == units.ChronoBoostEnergyCost {
+			name = units.ChronoBoost
+		}
+		abilities[name] = mechanicsAbility{
+			Abil: events.CmdAbil{AbilLink: ability.Link, AbilCmdIndex: ability.CmdIndex},
+		}
+	}
+
+	usage := abilityUsage{}
+	if err := Analyse(replay, &usage); err != nil {
+		return nil, err
+	}
+
+	if _, ok := abilities[units.SpawnLarva]; !ok {
+		usage.addMostUsed(abilities, units.SpawnLarva, usage.injects, queen, nil)
+	}
+	if _, ok := abilities[units.ChronoBoost]; !ok {
+		usage.addMostUsed(abilities, units.ChronoBoost, usage.chronoboosts, nexus, nil)
+	}
+	if _, ok := abilities[units.CalldownMULE]; !ok {
+		usage.addMostUsed(abilities, units.CalldownMULE, usage.mules, orbitalCommand, nil)
+	}
+	if _, ok := abilities[units.ScannerSweep]; !ok {
+		excluded := make(map[events.CmdAbil]bool)
+		if mule, ok := abilities[units.CalldownMULE]; ok {
+			excluded[mule.Abil] = true
+		}
+		usage.addMostUsed(abilities, units.ScannerSweep, usage.scans, orbitalCommand, excluded)
+	}
+
+	return abilities, nil
+}
+
+// Add the ability used most often in the given way, unless it is excluded,
+// was not used often enough, or too often without the caster of the given
+// ingame name selected.
+func (usage *abilityUsage) addMostUsed(abilities map[string]mechanicsAbility, name string, uses map[events.CmdAbil]int, caster string, excluded map[events.CmdAbil]bool) {
+	candidates := make([]events.CmdAbil, 0, len(uses))
+	for ability, count := range uses {
+		if count < minAbilityUses || excluded[ability] {
+			continue
+		}
+		if float64(usage.withCaster[caster][ability]) < minAbilityCasterRate*float64(usage.uses[ability]) {
+			continue
+		}
+		candidates = append(candidates, ability)
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	// Ordered by link and command index on ties, so the result does not
+	// depend on the map's order.
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if uses[a] != uses[b] {
+			return uses[a] > uses[b]
+		}
+		if a.AbilLink != b.AbilLink {
+			return a.AbilLink < b.AbilLink
+		}
+		return a.AbilCmdIndex < b.AbilCmdIndex
+	})
+	abilities[name] = mechanicsAbility{Abil: candidates[0], Guessed: true}
+}
+
+// Counts how often abilities were used in ways matching one of the race
+// mechanics. Commands are matched against the state *before* the first event
+// following them, so the types of units are the ones they had when the
+// command was issued.
+type abilityUsage struct {
+	commands    []playerCommand
+	nextCommand int
+
+	injects      map[events.CmdAbil]int
+	chronoboosts map[events.CmdAbil]int
+	mules        map[events.CmdAbil]int
+	scans        map[events.CmdAbil]int
+	// Uses of each ability overall, and with a unit of the given ingame
+	// name selected.
+	uses       map[events.CmdAbil]int
+	withCaster map[string]map[events.CmdAbil]int
+}
+
+func (usage *abilityUsage) Start(sim *Simulator) error {
+	usage.commands = sim.Replay.playerCommands()
+	usage.injects = make(map[events.CmdAbil]int)
+	usage.chronoboosts = make(map[events.CmdAbil]int)
+	usage.mules = make(map[events.CmdAbil]int)
+	usage.scans = make(map[events.CmdAbil]int)
+	usage.uses = make(map[events.CmdAbil]int)
+	usage.withCaster = map[string]map[events.CmdAbil]int{
+		queen:          make(map[events.CmdAbil]int),
+		nexus:          make(map[events.CmdAbil]int),
+		orbitalCommand: make(map[events.CmdAbil]int),
+	}
+
+	return nil
+}
+
+func (usage *abilityUsage) Observe(sim *Simulator, evt s2prot.Event) error {
+	usage.countCommands(sim, evt.Loop())
+
+	return nil
+}
+
+func (usage *abilityUsage) Finish(sim *Simulator) error {
+	usage.countCommands(sim, sim.Replay.Rep.Header.Loops()+1)
+
+	return nil
+}
+
+// Count all commands issued before the given loop.
+func (usage *abilityUsage) countCommands(sim *Simulator, loop int64) {
+	for usage.nextCommand < len(usage.commands) {
+		command := usage.commands[usage.nextCommand]
+		if command.Loop >= loop {
+			return
+		}
+		usage.nextCommand += 1
+
+		if command.Abil == nil || command.Smart {
+			continue
+		}
+		ability := *command.Abil
+
+		selected := make(map[string]bool)
+		for _, tag := range command.Selected {
+			if unit, ok := sim.IngameUnits[tag]; ok && unit.OwnerID == command.PlayerID {
+				selected[unit.Name] = true
+			}
+		}
+		usage.uses[ability] += 1
+		for caster, uses := range usage.withCaster {
+			if selected[caster] {
+				uses[ability] += 1
+			}
+		}
+
+		target, targeted := sim.IngameUnits[command.TargetTag]
+		switch {
+		case targeted && target.OwnerID == command.PlayerID:
+			if selected[queen] && injectableTownhalls[target.Name] {
+				usage.injects[ability] += 1
+			}
+			if _, building := sim.Replay.Catalog().Buildings[target.Name]; building && selected[nexus] {
+				usage.chronoboosts[ability] += 1
+			}
+		case targeted:
+			if selected[orbitalCommand] {
+				usage.mules[ability] += 1
+			}
+		case command.TargetPoint != nil:
+			if selected[orbitalCommand] {
+				usage.scans[ability] += 1
+			}
+		}
+	}
+}
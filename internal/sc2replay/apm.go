@@ -0,0 +1,181 @@
+package sc2replay
+
+// Counts of actions a player performed, by category.
+type ActionCounts struct {
+	// Commands given to units, eg move, attack, build
+	Commands int
+	// Changes of the selection
+	Selections int
+	// Setting, appending to or recalling control groups
+	ControlGroups int
+	// Setting or appending to control groups, including stealing units from
+	// other groups
+	ControlGroupAssignments int
+	// Camera movements. These do not count towards APM or EPM.
+	Camera int
+}
+
+// Actions as counted towards APM: everything but camera movements.
+func (counts *ActionCounts) Actions() int {
+	return counts.Commands + counts.Selections + counts.ControlGroups
+}
+
+// Actions as counted towards EPM: Commands and assigning control groups.
+// Selection changes and recalling control groups are considered
+// ineffective, as they are commonly spammed.
+func (counts *ActionCounts) EffectiveActions() int {
+	return counts.Commands + counts.ControlGroupAssignments
+}
+
+func (counts *ActionCounts) add(other ActionCounts) {
+	counts.Commands += other.Commands
+	counts.Selections += other.Selections
+	counts.ControlGroups += other.ControlGroups
+	counts.ControlGroupAssignments += other.ControlGroupAssignments
+	counts.Camera += other.Camera
+}
+
+type PlayerActions struct {
+	Total ActionCounts
+	// Actions within each ingame minute, starting with the first one. The
+	// last minute might be partial.
+	PerMinute []ActionCounts
+	// Ingame minutes the player was present for.
+	Minutes float64
+}
+
+// Average actions per minute.
+func (actions *PlayerActions) APM() float64 {
+	if actions.Minutes == 0 {
+		return 0
+	}
+
+	return float64(actions.Total.Actions()) / actions.Minutes
+}
+
+// Average effective actions per minute.
+func (actions *PlayerActions) EPM() float64 {
+	if actions.Minutes == 0 {
+		return 0
+	}
+
+	return float64(actions.Total.EffectiveActions()) / actions.Minutes
+}
+
+// Calculates actions per minute (APM) and effective actions per minute (EPM)
+// from the game events.
+type APM struct {
+	Replay *Replay
+
+	// Actions by player ID
+	Players map[int64]*PlayerActions
+}
+
+// Call this to calculate the actions of all players up until the given
+// amount of ticks. Pass a negative amount to include the whole replay.
+func (apm *APM) Calculate(ticks int64) error {
+	_, err := apm.CalculateAt([]int64{ticks})
+	return err
+}
+
+// Call this to calculate the actions of all players up until each of the
+// given amounts of ticks, which must be in ascending order. Game events are
+// processed only once, with a sample being taken whenever one of the ticks is
+// reached. Negative amounts include the whole replay. Players is set to the
+// last sample.
+func (apm *APM) CalculateAt(ticks []int64) ([]map[int64]*PlayerActions, error) {
+	apm.Players = make(map[int64]*PlayerActions)
+	samples := make([]map[int64]*PlayerActions, 0, len(ticks))
+
+	ticksPerSecond, err := apm.Replay.TicksPerSecond()
+	if err != nil {
+		return samples, err
+	}
+	ticksPerMinute := ticksPerSecond * 60
+
+	players := make(map[int64]*PlayerActions)
+	for playerID := range apm.Replay.Rep.TrackerEvts.PIDPlayerDescMap {
+		players[playerID] = &PlayerActions{}
+	}
+	playerIDs := apm.Replay.humanPlayerIDs()
+
+	// Loop at which a user left the game, after which they cannot perform
+	// any more actions.
+	leftAt := make(map[int64]int64)
+
+	evts := apm.Replay.Rep.GameEvts
+	next := 0
+	for _, t := range ticks {
+		if t < 0 || t > apm.Replay.Rep.Header.Loops() {
+			t = apm.Replay.Rep.Header.Loops()
+		}
+
+		for ; next < len(evts) && evts[next].Loop() <= t; next++ {
+			evt := evts[next]
+
+			playerID, ok := playerIDs[evt.UserID()]
+			if !ok {
+				// Observers
+				continue
+			}
+
+			counts := ActionCounts{}
+			switch eventType := evt.EvtType.Name; eventType {
+			case "Cmd":
+				counts.Commands = 1
+			case "SelectionDelta":
+				counts.Selections = 1
+			case "ControlGroupUpdate":
+				counts.ControlGroups = 1
+				switch evt.Int("controlGroupUpdate") {
+				case controlGroupSet, controlGroupAppend, controlGroupSetSteal, controlGroupAppendSteal:
+					counts.ControlGroupAssignments = 1
+				}
+			case "CameraUpdate":
+				counts.Camera = 1
+			case "GameUserLeave":
+				leftAt[playerID] = evt.Loop()
+				continue
+			default:
+				continue
+			}
+
+			actions := players[playerID]
+			actions.Total.add(counts)
+
+			minute := int(float64(evt.Loop()) / ticksPerMinute)
+			for len(actions.PerMinute) <= minute {
+				actions.PerMinute = append(actions.PerMinute, ActionCounts{})
+			}
+			actions.PerMinute[minute].add(counts)
+		}
+
+		samples = append(samples, sampleActions(players, leftAt, t, ticksPerMinute))
+	}
+
+	if len(samples) > 0 {
+		apm.Players = samples[len(samples)-1]
+	}
+
+	return samples, nil
+}
+
+// Copy the actions of each player as of the given amount of ticks.
+func sampleActions(players map[int64]*PlayerActions, leftAt map[int64]int64, ticks int64, ticksPerMinute float64) map[int64]*PlayerActions {
+	sample := make(map[int64]*PlayerActions, len(players))
+
+	for playerID, actions := range players {
+		end := ticks
+		if loop, ok := leftAt[playerID]; ok && loop < end {
+			end = loop
+		}
+
+		sample[playerID] = &PlayerActions{
+			Total:     actions.Total,
+			PerMinute: append([]ActionCounts(nil), actions.PerMinute...),
+			Minutes:   float64(end) / ticksPerMinute,
+		}
+	}
+
+	return sample
+}
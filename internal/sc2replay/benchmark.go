@@ -0,0 +1,104 @@
+package sc2replay
+
+// Step of a reference build order.
+type BenchmarkStep struct {
+	// Human-readable name of a unit, building or upgrade, as in the unit
+	// catalog
+	Name   string
+	Supply int
+	// Target time, in seconds of game time
+	Seconds int64
+}
+
+// How a player fared at a step of a benchmark.
+type BenchmarkResult struct {
+	Step       BenchmarkStep
+	TargetLoop int64
+	// Whether the player took the step at all. If so, the loop at which
+	// they started it, as per `BuildOrderItem.StartLoop`, and their supply
+	// at the time.
+	Done      bool
+	StartLoop int64
+	Supply    int
+}
+
+// Loops by which the player was behind the step's target time. Negative if
+// they were ahead.
+func (result *BenchmarkResult) Delay() int64 {
+	return result.StartLoop - result.TargetLoop
+}
+
+// Compares the build order of a player to a reference build order. The n-th
+// occurrence of a unit, building or upgrade in the benchmark is matched to
+// the n-th time the player started it.
+type Benchmark struct {
+	Replay   *Replay
+	PlayerID int64
+	Steps    []BenchmarkStep
+
+	PlayerName string
+	// Results in the order of the benchmark's steps
+	Results []BenchmarkResult
+}
+
+// Call this to compare the player's build order to the benchmark.
+func (bench *Benchmark) Generate() error {
+	buildOrder := BuildOrder{
+		Replay:   bench.Replay,
+		PlayerID: bench.PlayerID,
+	}
+	if err := buildOrder.Generate(); err != nil {
+		return err
+	}
+	bench.PlayerName = buildOrder.PlayerName
+
+	// Items of each name, in the order they were started, as the build
+	// order's items are ordered by their start.
+	byName := make(map[string][]BuildOrderItem)
+	for _, item := range buildOrder.Items {
+		byName[item.Name] = append(byName[item.Name], item)
+	}
+
+	bench.Results = make([]BenchmarkResult, 0, len(bench.Steps))
+	matched := make(map[string]int)
+	for _, step := range bench.Steps {
+		target, err := bench.Replay.TicksUntilSeconds(float64(step.Seconds))
+		if err != nil {
+			return err
+		}
+
+		result := BenchmarkResult{
+			Step:       step,
+			TargetLoop: target,
+		}
+		if i := matched[step.Name]; i < len(byName[step.Name]) {
+			item := byName[step.Name][i]
+			result.Done = true
+			result.StartLoop = item.StartLoop
+			result.Supply = item.IngameSupply()
+			matched[step.Name] += 1
+		}
+		bench.Results = append(bench.Results, result)
+	}
+
+	return nil
+}
+
+// Number of steps the player took, and their average delay in loops.
+// Negative if they were ahead on average.
+func (bench *Benchmark) Summary() (int, int64) {
+	done := 0
+	var delay int64
+	for i := range bench.Results {
+		if bench.Results[i].Done {
+			done += 1
+			delay += bench.Results[i].Delay()
+		}
+	}
+
+	if done == 0 {
+		return 0, 0
+	}
+
+	return done, delay / int64(done)
+}
@@ -0,0 +1,297 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/icza/s2prot"
+	"math"
+	"sort"
+)
+
+type BuildOrderItemKind int
+
+const (
+	BuildOrderUnit BuildOrderItemKind = iota
+	BuildOrderBuilding
+	BuildOrderUpgrade
+)
+
+func (kind BuildOrderItemKind) String() string {
+	switch kind {
+	case BuildOrderUnit:
+		return "Unit"
+	case BuildOrderBuilding:
+		return "Building"
+	case BuildOrderUpgrade:
+		return "Upgrade"
+	default:
+		return "Unknown"
+	}
+}
+
+// Morphs of buildings into other buildings which are considered a separate
+// step of a build order. Other type changes between known units or
+// buildings (eg gateway => warpgate, sieging tanks) are not.
+var buildOrderMorphs = map[string]bool{
+	"Lair":              true,
+	"Hive":              true,
+	"GreaterSpire":      true,
+	"OrbitalCommand":    true,
+	"PlanetaryFortress": true,
+}
+
+// Units and buildings which are not considered part of a build order, as
+// they would drown out everything else.
+var buildOrderIgnored = map[string]bool{
+	"CreepTumorBurrowed": true,
+}
+
+type BuildOrderItem struct {
+	Loop int64
+	// Loop at which the item was started. The replay only tells when
+	// produced units, morphs and upgrades finished, so for those it is
+	// estimated from their build time, or taken from the command which
+	// started the research.
+	StartLoop int64
+	Kind      BuildOrderItemKind
+	// Human-readable name
+	Name string
+	// Supply of the player at the moment the item was started. As there
+	// are units with 0.5 supply, this is a float. Use
+	// `BuildOrderItem.IngameSupply()` for the integer (rounded) supply.
+	Supply float64
+}
+
+// Return rounded supply as shown in-game
+func (item *BuildOrderItem) IngameSupply() int {
+	return int(math.Round(item.Supply))
+}
+
+type BuildOrder struct {
+	PlayerID   int64
+	PlayerName string
+	Replay     *Replay
+
+	// Items in the order they were started
+	Items []BuildOrderItem
+
+	// Upgrades researched by all players, to take their start from
+	researches UpgradeResearches
+	// Supply of the player before the events of each loop with events
+	supplies []supplySample
+}
+
+type supplySample struct {
+	Loop   int64
+	Supply float64
+}
+
+// Upgrade which finished at the given loop
+type finishedUpgrade struct {
+	Name     string
+	Finished int64
+}
+
+// Call this to generate the build order.
+//
+// Mind that the replay only contains tracker events for some of the steps
+// when they are *started*:
+// - Buildings and warped-in units are listed once construction starts
+// - Produced units are listed once they are finished
+// - Upgrades are listed once research finished
+// The start of produced units is estimated from their build time. Upgrades
+// are taken from `UpgradeResearches`, which finds the commands starting them.
+func (bo *BuildOrder) Generate() error {
+	bo.researches = UpgradeResearches{Replay: bo.Replay}
+
+	return Analyse(bo.Replay, &bo.researches, bo)
+}
+
+func (bo *BuildOrder) Start(sim *Simulator) error {
+	name, err := bo.Replay.PlayerName(bo.PlayerID)
+	bo.PlayerName = name
+	bo.Items = make([]BuildOrderItem, 0)
+	bo.supplies = make([]supplySample, 0)
+
+	return err
+}
+
+func (bo *BuildOrder) Observe(sim *Simulator, evt s2prot.Event) error {
+	if n := len(bo.supplies); n == 0 || bo.supplies[n-1].Loop != evt.Loop() {
+		bo.supplies = append(bo.supplies, supplySample{
+			Loop:   evt.Loop(),
+			Supply: sim.supplyOf(bo.PlayerID),
+		})
+	}
+
+	// Units existing at the start of the game are not part of the build
+	// order.
+	if evt.Loop() == 0 {
+		return nil
+	}
+
+	item, ok, err := bo.itemFromEvent(evt, sim)
+	if err != nil {
+		sim.Diagnose(SeverityError, evt, err.Error())
+	}
+	if ok {
+		bo.Items = append(bo.Items, item)
+	}
+
+	return nil
+}
+
+func (bo *BuildOrder) Finish(sim *Simulator) error {
+	starts := make(map[finishedUpgrade]int64)
+	for _, research := range bo.researches.Researches[bo.PlayerID] {
+		starts[finishedUpgrade{Name: research.Name, Finished: research.Finished}] = research.Started
+	}
+
+	for i := range bo.Items {
+		item := &bo.Items[i]
+		if item.Kind == BuildOrderUpgrade {
+			if start, ok := starts[finishedUpgrade{Name: item.Name, Finished: item.Loop}]; ok {
+				item.StartLoop = start
+			}
+		}
+
+		// Supply *before* the item was started, as is common when
+		// writing down build orders.
+		item.Supply = bo.supplyAt(sim, item.StartLoop)
+	}
+
+	// Items were added as they showed up in the replay, which for produced
+	// units and upgrades is when they finished.
+	sort.SliceStable(bo.Items, func(i, j int) bool {
+		return bo.Items[i].StartLoop < bo.Items[j].StartLoop
+	})
+
+	return nil
+}
+
+// Return the supply of the player before the events of the given loop, which
+// is the one before the events of the first loop with events following it.
+func (bo *BuildOrder) supplyAt(sim *Simulator, loop int64) float64 {
+	i := sort.Search(len(bo.supplies), func(i int) bool {
+		return bo.supplies[i].Loop >= loop
+	})
+	if i == len(bo.supplies) {
+		return sim.supplyOf(bo.PlayerID)
+	}
+
+	return bo.supplies[i].Supply
+}
+
+// Return the build order item corresponding to the event, if any. The passed
+// simulator must reflect the state *before* the event was handled.
+func (bo *BuildOrder) itemFromEvent(evt s2prot.Event, state *Simulator) (BuildOrderItem, bool, error) {
+	item := BuildOrderItem{Loop: evt.Loop(), StartLoop: evt.Loop()}
+
+	switch eventType := evt.EvtType.Name; eventType {
+	case "UnitBorn":
+		event := events.UnitBorn{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return item, false, fmt.Errorf("Unable to unmarshal UnitBorn event: %v", err)
+		}
+		if event.UpkeepPlayerID != bo.PlayerID {
+			return item, false, nil
+		}
+
+		return bo.enrichItem(item, event.UnitTypeName, true)
+	case "UnitInit":
+		event := events.UnitInit{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return item, false, fmt.Errorf("Unable to unmarshal UnitInit event: %v", err)
+		}
+		if event.UpkeepPlayerID != bo.PlayerID {
+			return item, false, nil
+		}
+
+		return bo.enrichItem(item, event.UnitTypeName, false)
+	case "UnitTypeChange":
+		event := events.UnitTypeChange{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return item, false, fmt.Errorf("Unable to unmarshal UnitTypeChange event: %v", err)
+		}
+
+		existing, ok := state.IngameUnits[unitTag(event.UnitTagIndex, event.UnitTagRecycle)]
+		if !ok || existing.OwnerID != bo.PlayerID {
+			return item, false, nil
+		}
+
+		// Units hatching from eggs or cocoons change their type from
+		// something we don't know about, to a known unit.
+		_, knownUnit := bo.Replay.Catalog().Units[existing.Name]
+		_, knownBuilding := bo.Replay.Catalog().Buildings[existing.Name]
+		if knownUnit || knownBuilding {
+			if !buildOrderMorphs[event.UnitTypeName] {
+				return item, false, nil
+			}
+		}
+
+		return bo.enrichItem(item, event.UnitTypeName, true)
+	case "Upgrade":
+		event := events.Upgrade{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return item, false, fmt.Errorf("Unable to unmarshal Upgrade event: %v", err)
+		}
+		if event.PlayerID != bo.PlayerID {
+			return item, false, nil
+		}
+
+		// Estimated unless `UpgradeResearches` finds the command which
+		// started it.
+		if upgrade, ok := bo.Replay.Catalog().Upgrades[event.UpgradeTypeName]; ok {
+			item.Kind = BuildOrderUpgrade
+			item.Name = upgrade.Name
+			item.StartLoop = bo.estimateStart(item.Loop, upgrade.ResearchTime)
+			return item, true, nil
+		}
+	}
+
+	return item, false, nil
+}
+
+// Fill in kind and name of a unit or building, as well as its start if the
+// event is of it being finished. Returns false if no static information is
+// available.
+func (bo *BuildOrder) enrichItem(item BuildOrderItem, name string, finished bool) (BuildOrderItem, bool, error) {
+	if buildOrderIgnored[name] {
+		return item, false, nil
+	}
+
+	if unit, ok := bo.Replay.Catalog().Units[name]; ok {
+		item.Kind = BuildOrderUnit
+		item.Name = unit.Name
+		if finished {
+			item.StartLoop = bo.estimateStart(item.Loop, unit.BuildTime)
+		}
+		return item, true, nil
+	}
+
+	if building, ok := bo.Replay.Catalog().Buildings[name]; ok {
+		item.Kind = BuildOrderBuilding
+		item.Name = building.Name
+		if finished {
+			item.StartLoop = bo.estimateStart(item.Loop, building.BuildTime)
+		}
+		return item, true, nil
+	}
+
+	return item, false, nil
+}
+
+// Estimate when something finishing at the given loop was started, given its
+// build or research time in seconds.
+func (bo *BuildOrder) estimateStart(loop int64, seconds int64) int64 {
+	ticks, err := bo.Replay.TicksUntilSeconds(float64(seconds))
+	if err != nil {
+		return loop
+	}
+	if ticks > loop {
+		return 0
+	}
+
+	return loop - ticks
+}
@@ -0,0 +1,123 @@
+package sc2replay
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+type Severity int
+
+const (
+	// Noteworthy, but does not affect results
+	SeverityInfo Severity = iota
+	// Results might be slightly off, eg a unit missing from counts
+	SeverityWarning
+	// Part of the replay could not be processed at all
+	SeverityError
+)
+
+// Diagnostics of at least this severity are logged as they are collected.
+var DiagnosticsLogLevel = SeverityWarning
+
+func (severity Severity) String() string {
+	switch severity {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("severity %d", int(severity))
+	}
+}
+
+// Parse the name of a severity, as returned by `Severity.String()`.
+func ParseSeverity(name string) (Severity, error) {
+	for _, severity := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		if strings.EqualFold(name, severity.String()) {
+			return severity, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown severity: %v", name)
+}
+
+// Something unexpected encountered while processing a replay, which did not
+// prevent processing, but might affect the results.
+type Diagnostic struct {
+	Severity Severity
+	// Loop of the event which caused it. Zero if not caused by an event.
+	Loop int64
+	// Name of the type of the event which caused it. Empty if not caused
+	// by an event.
+	EventType string
+	Message   string
+}
+
+func (diag Diagnostic) String() string {
+	if len(diag.EventType) == 0 {
+		return fmt.Sprintf("[%v] %v", diag.Severity, diag.Message)
+	}
+
+	return fmt.Sprintf("[%v] %v at loop %d: %v", diag.Severity, diag.EventType, diag.Loop, diag.Message)
+}
+
+// Collects diagnostics, in the order they occurred. Identical ones are only
+// collected once.
+type Diagnostics struct {
+	Entries []Diagnostic
+	seen    map[Diagnostic]bool
+}
+
+func (diags *Diagnostics) Add(diag Diagnostic) {
+	if diags.collect(diag) && diag.Severity >= DiagnosticsLogLevel {
+		log.Printf("Replay diagnostic: %v", diag)
+	}
+}
+
+// Collect diagnostics of another collector, eg of reports of multiple
+// players. As they were collected before, they are not logged again.
+func (diags *Diagnostics) Merge(entries []Diagnostic) {
+	for _, diag := range entries {
+		diags.collect(diag)
+	}
+}
+
+// Returns false if the diagnostic was collected before.
+func (diags *Diagnostics) collect(diag Diagnostic) bool {
+	if diags.seen == nil {
+		diags.seen = make(map[Diagnostic]bool)
+		for _, existing := range diags.Entries {
+			diags.seen[existing] = true
+		}
+	}
+	if diags.seen[diag] {
+		return false
+	}
+	diags.seen[diag] = true
+	diags.Entries = append(diags.Entries, diag)
+
+	return true
+}
+
+// Shorthand for adding a diagnostic which is not caused by an event.
+func (diags *Diagnostics) Addf(severity Severity, format string, args ...interface{}) {
+	diags.Add(Diagnostic{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Return the diagnostics of at least the given severity.
+func (diags *Diagnostics) AtLeast(severity Severity) []Diagnostic {
+	entries := make([]Diagnostic, 0)
+	for _, diag := range diags.Entries {
+		if diag.Severity >= severity {
+			entries = append(entries, diag)
+		}
+	}
+
+	return entries
+}
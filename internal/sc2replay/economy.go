@@ -0,0 +1,207 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/icza/s2prot"
+)
+
+// Supply values in PlayerStats events are fixed-point numbers.
+const foodScale = 4096.0
+
+// Economy of a player at one point in time, as sampled by the game in regular
+// intervals.
+type EconomySample struct {
+	Loop int64
+
+	Workers int64
+
+	// Supply used and supply provided. Zerglings and banelings make these
+	// fractional.
+	Supply    float64
+	SupplyCap float64
+
+	// Per minute
+	MineralsCollectionRate int64
+	VespeneCollectionRate  int64
+
+	// Unspent resources
+	MineralsCurrent int64
+	VespeneCurrent  int64
+
+	// Resources spent on the currently alive army
+	ArmyValueMinerals int64
+	ArmyValueVespene  int64
+}
+
+func sampleFromStats(loop int64, stats events.Stats) EconomySample {
+	return EconomySample{
+		Loop:                   loop,
+		Workers:                stats.WorkersActiveCount,
+		Supply:                 float64(stats.FoodUsed) / foodScale,
+		SupplyCap:              float64(stats.FoodMade) / foodScale,
+		MineralsCollectionRate: stats.MineralsCollectionRate,
+		VespeneCollectionRate:  stats.VespeneCollectionRate,
+		MineralsCurrent:        stats.MineralsCurrent,
+		VespeneCurrent:         stats.VespeneCurrent,
+		ArmyValueMinerals:      stats.MineralsUsedCurrentArmy,
+		ArmyValueVespene:       stats.VespeneUsedCurrentArmy,
+	}
+}
+
+// A value which can be extracted from economy samples, eg to plot it.
+type EconomyMetric struct {
+	Name  string
+	Value func(sample EconomySample) float64
+}
+
+// Available economy metrics, by short identifier.
+var EconomyMetrics = map[string]EconomyMetric{
+	"supply": EconomyMetric{
+		Name:  "Supply",
+		Value: func(s EconomySample) float64 { return s.Supply },
+	},
+	"workers": EconomyMetric{
+		Name:  "Workers",
+		Value: func(s EconomySample) float64 { return float64(s.Workers) },
+	},
+	"army": EconomyMetric{
+		Name:  "Army value",
+		Value: func(s EconomySample) float64 { return float64(s.ArmyValueMinerals + s.ArmyValueVespene) },
+	},
+	"income": EconomyMetric{
+		Name:  "Collection rate",
+		Value: func(s EconomySample) float64 { return float64(s.MineralsCollectionRate + s.VespeneCollectionRate) },
+	},
+	"bank": EconomyMetric{
+		Name:  "Unspent resources",
+		Value: func(s EconomySample) float64 { return float64(s.MineralsCurrent + s.VespeneCurrent) },
+	},
+}
+
+// Summary of several economy samples.
+type EconomySummary struct {
+	From EconomySample
+	To   EconomySample
+
+	AverageWorkers                float64
+	AverageMineralsCollectionRate float64
+	AverageVespeneCollectionRate  float64
+	AverageMineralsCurrent        float64
+	AverageVespeneCurrent         float64
+}
+
+type EconomyTimeline struct {
+	Replay *Replay
+
+	// Samples by player ID, in chronological order.
+	Samples map[int64][]EconomySample
+}
+
+// Call this to generate the timeline.
+func (timeline *EconomyTimeline) Generate() error {
+	return Analyse(timeline.Replay, timeline)
+}
+
+func (timeline *EconomyTimeline) Start(sim *Simulator) error {
+	timeline.Samples = make(map[int64][]EconomySample)
+
+	return nil
+}
+
+func (timeline *EconomyTimeline) Observe(sim *Simulator, evt s2prot.Event) error {
+	if evt.EvtType.Name != "PlayerStats" {
+		return nil
+	}
+
+	event, err := parsePlayerStats(evt)
+	if err != nil {
+		return err
+	}
+
+	timeline.Samples[event.PlayerID] = append(
+		timeline.Samples[event.PlayerID],
+		sampleFromStats(evt.Loop(), event.Stats),
+	)
+
+	return nil
+}
+
+func (timeline *EconomyTimeline) Finish(sim *Simulator) error {
+	return nil
+}
+
+// Return the most recent sample of the given player at the given loop.
+func (timeline *EconomyTimeline) At(playerID int64, loop int64) (EconomySample, bool) {
+	var sample EconomySample
+	found := false
+
+	for _, s := range timeline.Samples[playerID] {
+		if s.Loop > loop {
+			break
+		}
+		sample = s
+		found = true
+	}
+
+	return sample, found
+}
+
+// Return all samples of the given player within the given (inclusive) range
+// of loops.
+func (timeline *EconomyTimeline) Between(playerID int64, from int64, to int64) []EconomySample {
+	samples := make([]EconomySample, 0)
+
+	for _, s := range timeline.Samples[playerID] {
+		if s.Loop < from {
+			continue
+		}
+		if s.Loop > to {
+			break
+		}
+		samples = append(samples, s)
+	}
+
+	return samples
+}
+
+// Summarise the economy of the given player within the given (inclusive)
+// range of loops.
+func (timeline *EconomyTimeline) Summarise(playerID int64, from int64, to int64) (EconomySummary, error) {
+	summary := EconomySummary{}
+
+	samples := timeline.Between(playerID, from, to)
+	if len(samples) == 0 {
+		return summary, fmt.Errorf("No economy samples of player %d in given range", playerID)
+	}
+
+	summary.From = samples[0]
+	summary.To = samples[len(samples)-1]
+
+	for _, s := range samples {
+		summary.AverageWorkers += float64(s.Workers)
+		summary.AverageMineralsCollectionRate += float64(s.MineralsCollectionRate)
+		summary.AverageVespeneCollectionRate += float64(s.VespeneCollectionRate)
+		summary.AverageMineralsCurrent += float64(s.MineralsCurrent)
+		summary.AverageVespeneCurrent += float64(s.VespeneCurrent)
+	}
+
+	n := float64(len(samples))
+	summary.AverageWorkers /= n
+	summary.AverageMineralsCollectionRate /= n
+	summary.AverageVespeneCollectionRate /= n
+	summary.AverageMineralsCurrent /= n
+	summary.AverageVespeneCurrent /= n
+
+	return summary, nil
+}
+
+func parsePlayerStats(evt s2prot.Event) (events.PlayerStats, error) {
+	event := events.PlayerStats{}
+	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+		return event, fmt.Errorf("Unable to unmarshal PlayerStats event: %v", err)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,211 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/dragaera/probius/internal/sc2replay/units"
+	"github.com/icza/s2prot"
+	"math"
+	"sort"
+)
+
+// Deaths within this many seconds of the previous death of a fight, and
+// within this distance of the fight's location, are considered part of the
+// same fight.
+const engagementWindowSeconds float64 = 10
+const engagementRadius float64 = 15
+
+// Losses of one player within an engagement.
+type EngagementLosses struct {
+	// Count of units and buildings lost, by human-readable name
+	Units map[string]int
+	// Exact supply of units lost
+	Supply float64
+	// Resources spent on the units and buildings lost, as per the unit
+	// catalog
+	Resources int64
+}
+
+type Engagement struct {
+	Start int64
+	End   int64
+
+	// Average position of all deaths
+	X float64
+	Y float64
+
+	// Losses by player ID. Includes players who killed something, but did
+	// not lose anything.
+	Losses map[int64]*EngagementLosses
+
+	deaths int
+}
+
+// Total resources lost by all players.
+func (engagement *Engagement) ResourcesLost() int64 {
+	total := int64(0)
+	for _, losses := range engagement.Losses {
+		total += losses.Resources
+	}
+
+	return total
+}
+
+// Return the ID of the player who lost the least resources, and whether
+// there is such a player. There is none if there was a draw.
+func (engagement *Engagement) Winner() (int64, bool) {
+	playerIDs := make([]int64, 0, len(engagement.Losses))
+	for playerID := range engagement.Losses {
+		playerIDs = append(playerIDs, playerID)
+	}
+	sort.Slice(playerIDs, func(i, j int) bool {
+		return engagement.Losses[playerIDs[i]].Resources < engagement.Losses[playerIDs[j]].Resources
+	})
+
+	if len(playerIDs) == 0 {
+		return 0, false
+	}
+	if len(playerIDs) > 1 && engagement.Losses[playerIDs[0]].Resources == engagement.Losses[playerIDs[1]].Resources {
+		return 0, false
+	}
+
+	return playerIDs[0], true
+}
+
+func (engagement *Engagement) losses(playerID int64) *EngagementLosses {
+	losses, ok := engagement.Losses[playerID]
+	if !ok {
+		losses = &EngagementLosses{Units: make(map[string]int)}
+		engagement.Losses[playerID] = losses
+	}
+
+	return losses
+}
+
+// Groups deaths of units and buildings into engagements.
+type Engagements struct {
+	Replay *Replay
+
+	// Engagements in the order they started
+	Engagements []*Engagement
+
+	// Maximum ticks between deaths of the same engagement
+	window int64
+	// Engagements which might still have further deaths
+	ongoing []*Engagement
+}
+
+// Call this to detect engagements.
+func (eng *Engagements) Generate() error {
+	return Analyse(eng.Replay, eng)
+}
+
+func (eng *Engagements) Start(sim *Simulator) error {
+	eng.Engagements = make([]*Engagement, 0)
+	eng.ongoing = make([]*Engagement, 0)
+
+	window, err := eng.Replay.TicksUntilSeconds(engagementWindowSeconds)
+	if err != nil {
+		return err
+	}
+	eng.window = window
+
+	return nil
+}
+
+func (eng *Engagements) Observe(sim *Simulator, evt s2prot.Event) error {
+	if evt.EvtType.Name != "UnitDied" {
+		return nil
+	}
+
+	event := events.UnitDied{}
+	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+		return fmt.Errorf("Unable to unmarshal UnitDied event: %v", err)
+	}
+
+	// Owner and name of the unit are only known until it's removed
+	unit, ok := sim.IngameUnits[unitTag(event.UnitTagIndex, event.UnitTagRecycle)]
+	if ok {
+		eng.ongoing = eng.trackDeath(evt.Loop(), eng.window, event, unit, eng.ongoing)
+	}
+
+	return nil
+}
+
+func (eng *Engagements) Finish(sim *Simulator) error {
+	return nil
+}
+
+// Add a death to a matching ongoing engagement, or start a new one. Returns
+// the engagements which are still ongoing.
+func (eng *Engagements) trackDeath(loop int64, window int64, event events.UnitDied, unit IngameUnit, ongoing []*Engagement) []*Engagement {
+	// Units dying on their own, eg High Templars merging into an Archon,
+	// or neutral units, are not part of any fight.
+	if event.KillerPlayerID == nil || *event.KillerPlayerID == 0 || unit.OwnerID == 0 {
+		return ongoing
+	}
+
+	name := ""
+	count := 1
+	supply := 0.0
+	cost := units.Cost{}
+	catalog := eng.Replay.Catalog()
+	if target, ok := morphingUnit(catalog, unit); ok {
+		// Eggs and cocoons are lost along with what they were
+		// morphing into.
+		name = target.Name
+		count = unit.MorphCount
+		supply = target.Supply
+		cost = target.Cost
+	} else if enrichedUnit, ok := catalog.Units[unit.Name]; ok {
+		name = enrichedUnit.Name
+		supply = enrichedUnit.Supply
+		cost = enrichedUnit.Cost
+	} else if enrichedBuilding, ok := catalog.Buildings[unit.Name]; ok {
+		name = enrichedBuilding.Name
+		cost = enrichedBuilding.Cost
+	} else {
+		// Eg larva, interceptors, broodlings
+		return ongoing
+	}
+
+	stillOngoing := make([]*Engagement, 0, len(ongoing))
+	var match *Engagement
+	for _, engagement := range ongoing {
+		if loop-engagement.End > window {
+			continue
+		}
+		stillOngoing = append(stillOngoing, engagement)
+
+		distance := math.Hypot(engagement.X-float64(event.X), engagement.Y-float64(event.Y))
+		if match == nil && distance <= engagementRadius {
+			match = engagement
+		}
+	}
+
+	if match == nil {
+		match = &Engagement{
+			Start:  loop,
+			X:      float64(event.X),
+			Y:      float64(event.Y),
+			Losses: make(map[int64]*EngagementLosses),
+		}
+		eng.Engagements = append(eng.Engagements, match)
+		stillOngoing = append(stillOngoing, match)
+	}
+
+	// Moving average of the fight's location
+	match.deaths += 1
+	match.X += (float64(event.X) - match.X) / float64(match.deaths)
+	match.Y += (float64(event.Y) - match.Y) / float64(match.deaths)
+	match.End = loop
+
+	losses := match.losses(unit.OwnerID)
+	losses.Units[name] += count
+	losses.Supply += supply
+	losses.Resources += cost.Total()
+	match.losses(*event.KillerPlayerID)
+
+	return stillOngoing
+}
@@ -1,5 +1,10 @@
 package events
 
+import (
+	"encoding/hex"
+	"strings"
+)
+
 type GameUserLeave struct {
 	BaseEvent
 	LeaveReason int    `json:"leaveReason"`
@@ -10,3 +15,105 @@ type GameUserLeave struct {
 type UserID struct {
 	UserID int64 `json:"userId"`
 }
+
+type Cmd struct {
+	BaseEvent
+	UserID   UserID `json:"userid"`
+	CmdFlags int64  `json:"cmdFlags"`
+	// Nil for commands without an ability, eg right-clicks
+	Abil *CmdAbil `json:"abil"`
+	Data CmdData  `json:"data"`
+}
+
+type CmdAbil struct {
+	AbilLink     int64 `json:"abilLink"`
+	AbilCmdIndex int64 `json:"abilCmdIndex"`
+}
+
+type CmdData struct {
+	// Nil unless a unit was targeted
+	TargetUnit *CmdTargetUnit `json:"TargetUnit"`
+	// Nil unless a point was targeted
+	TargetPoint *CmdTargetPoint `json:"TargetPoint"`
+}
+
+type CmdTargetUnit struct {
+	Tag int64 `json:"tag"`
+}
+
+// In fixed point, with 4096 units per cell of the map.
+type CmdTargetPoint struct {
+	X int64 `json:"x"`
+	Y int64 `json:"y"`
+	Z int64 `json:"z"`
+}
+
+type SelectionDelta struct {
+	BaseEvent
+	UserID UserID `json:"userid"`
+	// Either a control group, or ActiveSelection
+	ControlGroupID int64              `json:"controlGroupId"`
+	Delta          SelectionDeltaData `json:"delta"`
+}
+
+type SelectionDeltaData struct {
+	SubgroupIndex int64 `json:"subgroupIndex"`
+	// Applied before units are added
+	RemoveMask   SelectionMask       `json:"removeMask"`
+	AddSubgroups []SelectionSubgroup `json:"addSubgroups"`
+	// Tags of the added units
+	AddUnitTags []int64 `json:"addUnitTags"`
+}
+
+type SelectionSubgroup struct {
+	Count                 int64 `json:"count"`
+	IntraSubgroupPriority int64 `json:"intraSubgroupPriority"`
+	SubgroupPriority      int64 `json:"subgroupPriority"`
+	UnitLink              int64 `json:"unitLink"`
+}
+
+// Units to remove from a selection, by their index within it. At most one of
+// the fields is set, if none is nothing is removed.
+type SelectionMask struct {
+	// Units whose bit is set are removed
+	Mask *BitArray `json:"Mask"`
+	// Indices of the units to remove
+	OneIndices []int64 `json:"OneIndices"`
+	// Indices of the units to keep. Empty, but not nil, if all units are
+	// removed.
+	ZeroIndices []int64 `json:"ZeroIndices"`
+}
+
+// Bits of a bit array, as encoded by s2prot.
+type BitArray struct {
+	Count int64 `json:"Count"`
+	// Hex-encoded, prefixed with `0x`, starting with the lowest bits.
+	Data string `json:"Data"`
+}
+
+// Return whether each of the bits is set. Malformed data is treated as no bits
+// being set.
+func (arr *BitArray) Bits() []bool {
+	bits := make([]bool, arr.Count)
+
+	data, err := hex.DecodeString(strings.TrimPrefix(arr.Data, "0x"))
+	if err != nil {
+		return bits
+	}
+	for i := range bits {
+		if i>>3 < len(data) {
+			bits[i] = data[i>>3]&(1<<uint(i&7)) != 0
+		}
+	}
+
+	return bits
+}
+
+type ControlGroupUpdate struct {
+	BaseEvent
+	UserID             UserID `json:"userid"`
+	ControlGroupIndex  int64  `json:"controlGroupIndex"`
+	ControlGroupUpdate int64  `json:"controlGroupUpdate"`
+	// Units to remove from the control group
+	Mask SelectionMask `json:"mask"`
+}
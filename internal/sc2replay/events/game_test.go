@@ -0,0 +1,53 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBitArrayBits(t *testing.T) {
+	tests := []struct {
+		name string
+		arr  BitArray
+		want []bool
+	}{
+		{
+			name: "empty",
+			arr:  BitArray{Count: 0, Data: "0x"},
+			want: []bool{},
+		},
+		{
+			name: "lowest bits first",
+			arr:  BitArray{Count: 4, Data: "0x05"},
+			want: []bool{true, false, true, false},
+		},
+		{
+			name: "spanning bytes",
+			arr:  BitArray{Count: 10, Data: "0x0102"},
+			want: []bool{true, false, false, false, false, false, false, false, false, true},
+		},
+		{
+			name: "without prefix",
+			arr:  BitArray{Count: 3, Data: "06"},
+			want: []bool{false, true, true},
+		},
+		{
+			name: "fewer bytes than bits",
+			arr:  BitArray{Count: 10, Data: "0xff"},
+			want: []bool{true, true, true, true, true, true, true, true, false, false},
+		},
+		{
+			name: "malformed",
+			arr:  BitArray{Count: 3, Data: "0xzz"},
+			want: []bool{false, false, false},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.arr.Bits(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Bits() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package events
+
+// Recipients of chat messages and pings, as per s2protocol.
+const (
+	RecipientAll       = 0
+	RecipientAllies    = 2
+	RecipientObservers = 4
+)
+
+type Chat struct {
+	BaseEvent
+	UserID    UserID `json:"userid"`
+	Recipient int64  `json:"recipient"`
+	String    string `json:"string"`
+}
+
+type Ping struct {
+	BaseEvent
+	UserID    UserID `json:"userid"`
+	Recipient int64  `json:"recipient"`
+}
@@ -123,6 +123,32 @@ type UnitPositions struct {
 	Items          []int64 `json:"items"`
 }
 
+// Position of a single unit, as decoded from a UnitPositions event.
+type UnitPosition struct {
+	UnitTagIndex int64
+	WithPosition
+}
+
+// Decode the packed positions. Items come in triplets of (unit index delta,
+// x, y), with coordinates scaled down by a factor of four.
+func (event *UnitPositions) Positions() []UnitPosition {
+	positions := make([]UnitPosition, 0, len(event.Items)/3)
+
+	index := event.FirstUnitIndex
+	for i := 0; i+2 < len(event.Items); i += 3 {
+		index += event.Items[i]
+		positions = append(positions, UnitPosition{
+			UnitTagIndex: index,
+			WithPosition: WithPosition{
+				X: event.Items[i+1] * 4,
+				Y: event.Items[i+2] * 4,
+			},
+		})
+	}
+
+	return positions
+}
+
 type UnitOwnerChange struct {
 	BaseEvent
 	WithUnitTag
@@ -155,7 +181,7 @@ type Stats struct {
 
 	MineralsFriendlyFireArmy       int64 `json:"scoreValueMineralsFriendlyFireArmy"`
 	MineralsFriendlyFireEconomy    int64 `json:"scoreValueMineralsFriendlyFireEconomy"`
-	MineralsFriendlyFireTechnology int64 `json:"scoreValueMineralsFriendlyFireEconomy"`
+	MineralsFriendlyFireTechnology int64 `json:"scoreValueMineralsFriendlyFireTechnology"`
 
 	MineralsKilledArmy       int64 `json:"scoreValueMineralsKilledArmy"`
 	MineralsKilledEconomy    int64 `json:"scoreValueMineralsKilledEconomy"`
@@ -179,7 +205,7 @@ type Stats struct {
 
 	VespeneFriendlyFireArmy       int64 `json:"scoreValueVespeneFriendlyFireArmy"`
 	VespeneFriendlyFireEconomy    int64 `json:"scoreValueVespeneFriendlyFireEconomy"`
-	VespeneFriendlyFireTechnology int64 `json:"scoreValueVespeneFriendlyFireEconomy"`
+	VespeneFriendlyFireTechnology int64 `json:"scoreValueVespeneFriendlyFireTechnology"`
 
 	VespeneKilledArmy       int64 `json:"scoreValueVespeneKilledArmy"`
 	VespeneKilledEconomy    int64 `json:"scoreValueVespeneKilledEconomy"`
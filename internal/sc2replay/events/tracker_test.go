@@ -0,0 +1,50 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnitPositionsPositions(t *testing.T) {
+	tests := []struct {
+		name  string
+		event UnitPositions
+		want  []UnitPosition
+	}{
+		{
+			name:  "empty",
+			event: UnitPositions{FirstUnitIndex: 10},
+			want:  []UnitPosition{},
+		},
+		{
+			name:  "single unit",
+			event: UnitPositions{FirstUnitIndex: 10, Items: []int64{0, 5, 7}},
+			want: []UnitPosition{
+				{UnitTagIndex: 10, WithPosition: WithPosition{X: 20, Y: 28}},
+			},
+		},
+		{
+			name:  "index deltas",
+			event: UnitPositions{FirstUnitIndex: 10, Items: []int64{2, 1, 1, 3, 10, 20}},
+			want: []UnitPosition{
+				{UnitTagIndex: 12, WithPosition: WithPosition{X: 4, Y: 4}},
+				{UnitTagIndex: 15, WithPosition: WithPosition{X: 40, Y: 80}},
+			},
+		},
+		{
+			name:  "incomplete triplet",
+			event: UnitPositions{FirstUnitIndex: 10, Items: []int64{0, 5, 7, 1, 2}},
+			want: []UnitPosition{
+				{UnitTagIndex: 10, WithPosition: WithPosition{X: 20, Y: 28}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.event.Positions(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Positions() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/icza/s2prot"
+)
+
+// Ingame names of the townhalls which can be built. Upgraded townhalls (eg
+// Lair, Orbital Command) are morphed from those, and keep their unit tag.
+var townhalls = map[string]bool{
+	"Nexus":         true,
+	"CommandCenter": true,
+	"Hatchery":      true,
+}
+
+type Expansion struct {
+	// Human-readable name of the townhall
+	Name string
+	X    int64
+	Y    int64
+
+	// Loops at which the townhall was started, finished, and lost or
+	// cancelled. Finished, Lost and Cancelled are zero if it never was.
+	Started   int64
+	Finished  int64
+	Lost      int64
+	Cancelled int64
+}
+
+// Finds all townhalls which were built during the game, that is excluding
+// the ones players start with. Those are tracked separately, only to tell
+// when they were lost.
+type Expansions struct {
+	Replay *Replay
+
+	// Expansions by player ID, in the order they were started.
+	Expansions map[int64][]*Expansion
+	// Townhalls players started with, by player ID. Started and Finished
+	// are zero.
+	Mains map[int64]*Expansion
+
+	// Expansions by unit tag, to track their completion and destruction.
+	byTag map[int64]*Expansion
+}
+
+// Call this to find the expansions.
+func (exp *Expansions) Generate() error {
+	return Analyse(exp.Replay, exp)
+}
+
+func (exp *Expansions) Start(sim *Simulator) error {
+	exp.Expansions = make(map[int64][]*Expansion)
+	exp.Mains = make(map[int64]*Expansion)
+	exp.byTag = make(map[int64]*Expansion)
+
+	return nil
+}
+
+func (exp *Expansions) Observe(sim *Simulator, evt s2prot.Event) error {
+	switch eventType := evt.EvtType.Name; eventType {
+	case "UnitInit":
+		event := events.UnitInit{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal UnitInit event: %v", err)
+		}
+
+		if expansion, ok := exp.newExpansion(evt.Loop(), event.UnitTypeName, event.WithPosition); ok {
+			exp.byTag[unitTag(event.UnitTagIndex, event.UnitTagRecycle)] = expansion
+			exp.Expansions[event.UpkeepPlayerID] = append(exp.Expansions[event.UpkeepPlayerID], expansion)
+		}
+	case "UnitBorn":
+		event := events.UnitBorn{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal UnitBorn event: %v", err)
+		}
+
+		if expansion, ok := exp.newExpansion(evt.Loop(), event.UnitTypeName, event.WithPosition); ok {
+			exp.byTag[unitTag(event.UnitTagIndex, event.UnitTagRecycle)] = expansion
+			if evt.Loop() == 0 {
+				exp.Mains[event.UpkeepPlayerID] = expansion
+			} else {
+				// Born units are finished right away
+				expansion.Finished = evt.Loop()
+				exp.Expansions[event.UpkeepPlayerID] = append(exp.Expansions[event.UpkeepPlayerID], expansion)
+			}
+		}
+	case "UnitDone":
+		event := events.UnitDone{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal UnitDone event: %v", err)
+		}
+
+		// Upgrading eg a Hatchery to a Lair causes UnitDone
+		// events as well.
+		if expansion, ok := exp.byTag[unitTag(event.UnitTagIndex, event.UnitTagRecycle)]; ok && expansion.Finished == 0 {
+			expansion.Finished = evt.Loop()
+		}
+	case "UnitDied":
+		event := events.UnitDied{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal UnitDied event: %v", err)
+		}
+
+		tag := unitTag(event.UnitTagIndex, event.UnitTagRecycle)
+		if expansion, ok := exp.byTag[tag]; ok {
+			// Cancelling a townhall under construction kills it, without
+			// anyone having killed it.
+			if expansion.Finished == 0 && event.KillerPlayerID == nil {
+				expansion.Cancelled = evt.Loop()
+			} else {
+				expansion.Lost = evt.Loop()
+			}
+			// Tags are recycled
+			delete(exp.byTag, tag)
+		}
+	}
+
+	return nil
+}
+
+func (exp *Expansions) Finish(sim *Simulator) error {
+	return nil
+}
+
+func (exp *Expansions) newExpansion(loop int64, name string, position events.WithPosition) (*Expansion, bool) {
+	if !townhalls[name] {
+		return nil, false
+	}
+
+	expansion := Expansion{
+		Name:    exp.Replay.Catalog().Buildings[name].Name,
+		X:       position.X,
+		Y:       position.Y,
+		Started: loop,
+	}
+
+	return &expansion, true
+}
@@ -0,0 +1,171 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/dragaera/probius/internal/sc2replay/units"
+	"github.com/icza/s2prot"
+)
+
+// Margin around the positions units were seen at, by which the playable area
+// of the map is assumed to exceed them.
+const heatmapMargin = 4
+
+// Counts how often a player's army units were seen at each position of the
+// map.
+//
+// Mind that the game does not record the positions of all units at all
+// times. Rather, it periodically records the positions of units which
+// recently took or dealt damage.
+//
+// The heatmap covers the playable area of the map only. As replays do not
+// record it, it is estimated from the positions all units, including neutral
+// ones such as mineral fields and rocks, were seen at.
+type Heatmap struct {
+	PlayerID int64
+	Replay   *Replay
+
+	// Bottom left corner of the area covered, in map coordinates
+	X int
+	Y int
+	// Dimensions of the area covered
+	Width  int
+	Height int
+
+	// Counts by position relative to the bottom left corner, row by row,
+	// starting at the bottom left (as per SC2's coordinate system).
+	Counts []int
+	// Highest count of all positions
+	Max int
+
+	// Positions only refer to the index part of unit tags, so we need to
+	// know which unit currently uses a given index.
+	tagsByIndex map[int64]int64
+	// Size of the map, and the bounds of all positions seen
+	mapWidth, mapHeight    int
+	minX, minY, maxX, maxY int64
+	positions              []events.WithPosition
+}
+
+// Call this to generate the heatmap.
+func (heatmap *Heatmap) Generate() error {
+	return Analyse(heatmap.Replay, heatmap)
+}
+
+func (heatmap *Heatmap) Start(sim *Simulator) error {
+	heatmap.mapWidth = int(heatmap.Replay.Rep.InitData.GameDescription.MapSizeX())
+	heatmap.mapHeight = int(heatmap.Replay.Rep.InitData.GameDescription.MapSizeY())
+	if heatmap.mapWidth <= 0 || heatmap.mapHeight <= 0 {
+		return fmt.Errorf("Invalid map size: %dx%d", heatmap.mapWidth, heatmap.mapHeight)
+	}
+
+	heatmap.Counts = nil
+	heatmap.Max = 0
+	heatmap.tagsByIndex = make(map[int64]int64)
+	heatmap.minX, heatmap.minY = int64(heatmap.mapWidth), int64(heatmap.mapHeight)
+	heatmap.maxX, heatmap.maxY = -1, -1
+	heatmap.positions = nil
+
+	return nil
+}
+
+func (heatmap *Heatmap) Observe(sim *Simulator, evt s2prot.Event) error {
+	switch evt.EvtType.Name {
+	case "UnitBorn", "UnitInit":
+		event := struct {
+			events.WithUnitTag
+			events.WithPosition
+		}{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal unit tag: %v", err)
+		}
+		heatmap.tagsByIndex[event.UnitTagIndex] = unitTag(event.UnitTagIndex, event.UnitTagRecycle)
+		heatmap.extend(event.WithPosition)
+	case "UnitPositions":
+		event := events.UnitPositions{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal UnitPositions event: %v", err)
+		}
+
+		for _, position := range event.Positions() {
+			heatmap.extend(position.WithPosition)
+
+			unit, ok := sim.IngameUnits[heatmap.tagsByIndex[position.UnitTagIndex]]
+			if !ok || unit.OwnerID != heatmap.PlayerID {
+				continue
+			}
+			if _, isUnit := heatmap.Replay.Catalog().Units[unit.Name]; !isUnit || units.Workers[unit.Name] {
+				continue
+			}
+
+			heatmap.positions = append(heatmap.positions, position.WithPosition)
+		}
+	}
+
+	return nil
+}
+
+func (heatmap *Heatmap) Finish(sim *Simulator) error {
+	heatmap.X, heatmap.Y = 0, 0
+	heatmap.Width, heatmap.Height = heatmap.mapWidth, heatmap.mapHeight
+	if heatmap.maxX >= 0 {
+		heatmap.X = clamp(int(heatmap.minX)-heatmapMargin, 0, heatmap.mapWidth-1)
+		heatmap.Y = clamp(int(heatmap.minY)-heatmapMargin, 0, heatmap.mapHeight-1)
+		heatmap.Width = clamp(int(heatmap.maxX)+heatmapMargin+1, 1, heatmap.mapWidth) - heatmap.X
+		heatmap.Height = clamp(int(heatmap.maxY)+heatmapMargin+1, 1, heatmap.mapHeight) - heatmap.Y
+	}
+
+	heatmap.Counts = make([]int, heatmap.Width*heatmap.Height)
+	for _, position := range heatmap.positions {
+		heatmap.add(position.X, position.Y)
+	}
+
+	return nil
+}
+
+// Extend the bounds of all positions seen by the given one, if it is on the
+// map.
+func (heatmap *Heatmap) extend(position events.WithPosition) {
+	if position.X < 0 || position.Y < 0 || int(position.X) >= heatmap.mapWidth || int(position.Y) >= heatmap.mapHeight {
+		return
+	}
+
+	if position.X < heatmap.minX {
+		heatmap.minX = position.X
+	}
+	if position.X > heatmap.maxX {
+		heatmap.maxX = position.X
+	}
+	if position.Y < heatmap.minY {
+		heatmap.minY = position.Y
+	}
+	if position.Y > heatmap.maxY {
+		heatmap.maxY = position.Y
+	}
+}
+
+// Count the given position, given in map coordinates.
+func (heatmap *Heatmap) add(x int64, y int64) {
+	x -= int64(heatmap.X)
+	y -= int64(heatmap.Y)
+	if x < 0 || y < 0 || int(x) >= heatmap.Width || int(y) >= heatmap.Height {
+		return
+	}
+
+	i := int(y)*heatmap.Width + int(x)
+	heatmap.Counts[i] += 1
+	if heatmap.Counts[i] > heatmap.Max {
+		heatmap.Max = heatmap.Counts[i]
+	}
+}
+
+func clamp(value int, min int, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
@@ -0,0 +1,514 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/dragaera/probius/internal/sc2replay/units"
+	"github.com/icza/s2prot"
+)
+
+// Ingame names of the townhalls queens can inject.
+var injectableTownhalls = map[string]bool{
+	"Hatchery": true,
+	"Lair":     true,
+	"Hive":     true,
+}
+
+const (
+	queen          = "Queen"
+	nexus          = "Nexus"
+	commandCenter  = "CommandCenter"
+	orbitalCommand = "OrbitalCommand"
+	mule           = "MULE"
+)
+
+// Queen injects into a single hatchery, lair or hive.
+type InjectedTownhall struct {
+	// Human-readable name, as of the most recent inject
+	Name        string
+	FirstInject int64
+	Injects     int
+	// Loops during which larvae were spawning, and loops from the first
+	// inject until the townhall died or the analysis ended.
+	InjectedLoops int64
+	ActiveLoops   int64
+
+	// Loop until which it is injected, including queued injects
+	injectedUntil int64
+	// Loop at which it died, zero if it did not
+	diedAt int64
+}
+
+// Share of the time since the first inject during which larvae were
+// spawning.
+func (townhall *InjectedTownhall) Uptime() float64 {
+	if townhall.ActiveLoops == 0 {
+		return 0
+	}
+
+	return float64(townhall.InjectedLoops) / float64(townhall.ActiveLoops)
+}
+
+// Nexus which cast chronoboost.
+type Nexus struct {
+	FirstChronoboost int64
+	Chronoboosts     int
+	// Chronoboosts by human-readable name of the building they targeted
+	Targets map[string]int
+}
+
+type Orbital struct {
+	// Loop at which it was morphed from a command center
+	Since int64
+	MULEs int
+	Scans int
+
+	tag int64
+	// Where it was built as a command center
+	x    int64
+	y    int64
+	lost bool
+}
+
+// Usage of race mechanics by a single player. Only the ones of the player's
+// race are of interest, the others are simply empty.
+type PlayerMechanics struct {
+	Race string
+
+	// Injected townhalls, in the order they were first injected
+	Townhalls []*InjectedTownhall
+
+	// Nexuses which cast chronoboost, in the order they first did
+	Nexuses []*Nexus
+	// Chronoboosts of all nexuses, including ones which could not be
+	// attributed to one, by human-readable name of the building they
+	// targeted.
+	Chronoboosts       int
+	ChronoboostTargets map[string]int
+
+	// Orbital commands, in the order they were morphed
+	Orbitals []*Orbital
+	// MULEs and scans of all orbital commands, including ones which could
+	// not be attributed to one.
+	MULEs int
+	Scans int
+}
+
+// Ability used by a player, as decoded from the game events.
+type abilityCommand struct {
+	Loop     int64
+	PlayerID int64
+	// Ingame name of the ability
+	Ability string
+	// Zero if no unit was targeted
+	TargetTag int64
+	// Tags of the selected units
+	Selected []int64
+}
+
+// Calculates how players used queen injects, chronoboost, MULEs and scans.
+//
+// Injects, chronoboosts and scans are decoded from the commands in the game
+// events, which can only be recognized if the catalog of the replay's base
+// build knows their abilities, or they can be guessed by how they were used.
+// See `findMechanicsAbilities`. MULEs are taken from the tracker events, and
+// are thus always available.
+//
+// As the game events do not tell which building cast them, chronoboosts and
+// scans are attributed to the selected nexus or orbital command which cast
+// least recently, and MULEs to the orbital command which called them down if
+// known, and to the one closest to where they landed otherwise.
+type Mechanics struct {
+	Replay *Replay
+	// Loop up until which the mechanics were calculated
+	Ticks int64
+
+	// Mechanics by player ID
+	Players map[int64]*PlayerMechanics
+
+	// Commands in chronological order, and the index of the next one to
+	// apply.
+	commands    []abilityCommand
+	nextCommand int
+	// Duration of an inject in loops
+	injectDuration int64
+
+	// Injected townhalls, nexuses and orbital commands by unit tag
+	townhalls map[int64]*InjectedTownhall
+	nexuses   map[int64]*Nexus
+	orbitals  map[int64]*Orbital
+	// Loop at which nexuses and orbital commands last cast an ability, by
+	// unit tag
+	lastCasts map[int64]int64
+	// Positions at which command centers were built, by unit tag
+	positions map[int64]events.WithPosition
+}
+
+// Call this to calculate mechanics up until the given amount of ticks. Pass
+// a negative amount to include the whole replay.
+func (mech *Mechanics) Calculate(ticks int64) error {
+	if ticks < 0 || ticks > mech.Replay.Rep.Header.Loops() {
+		ticks = mech.Replay.Rep.Header.Loops()
+	}
+	mech.Ticks = ticks
+
+	sim := NewSimulator(mech.Replay)
+	if err := sim.AddObserver(mech); err != nil {
+		return err
+	}
+	if err := sim.AdvanceTo(ticks); err != nil {
+		return err
+	}
+
+	return mech.Finish(sim)
+}
+
+// Whether the ability with the given ingame name can be recognized in the
+// replay's game events.
+func (mech *Mechanics) Supports(ability string) bool {
+	abilities, err := mech.Replay.mechanicsAbilities()
+	if err != nil {
+		return false
+	}
+	_, ok := abilities[ability]
+
+	return ok
+}
+
+// Whether the ability with the given ingame name is not known to the catalog
+// of the replay's base build, and was instead guessed by how it was used. See
+// `findMechanicsAbilities`.
+func (mech *Mechanics) Guessed(ability string) bool {
+	abilities, err := mech.Replay.mechanicsAbilities()
+	if err != nil {
+		return false
+	}
+
+	return abilities[ability].Guessed
+}
+
+func (mech *Mechanics) Start(sim *Simulator) error {
+	if mech.Ticks == 0 {
+		mech.Ticks = mech.Replay.Rep.Header.Loops()
+	}
+
+	mech.Players = make(map[int64]*PlayerMechanics)
+	for playerID := range mech.Replay.Rep.TrackerEvts.PIDPlayerDescMap {
+		player := PlayerMechanics{
+			Townhalls:          make([]*InjectedTownhall, 0),
+			Nexuses:            make([]*Nexus, 0),
+			ChronoboostTargets: make(map[string]int),
+			Orbitals:           make([]*Orbital, 0),
+		}
+		if details, ok := mech.Replay.detailsPlayer(playerID); ok {
+			player.Race = details.Race().Name
+		}
+		mech.Players[playerID] = &player
+	}
+
+	mech.townhalls = make(map[int64]*InjectedTownhall)
+	mech.nexuses = make(map[int64]*Nexus)
+	mech.orbitals = make(map[int64]*Orbital)
+	mech.lastCasts = make(map[int64]int64)
+	mech.positions = make(map[int64]events.WithPosition)
+
+	injectDuration, err := mech.Replay.TicksUntilSeconds(float64(mech.Replay.Catalog().Abilities[units.SpawnLarva].Duration))
+	if err != nil {
+		return err
+	}
+	mech.injectDuration = injectDuration
+
+	commands, err := mech.decodeCommands()
+	mech.commands = commands
+
+	return err
+}
+
+func (mech *Mechanics) Observe(sim *Simulator, evt s2prot.Event) error {
+	// Commands must be applied to the state *before* the event.
+	mech.applyCommands(sim, evt.Loop())
+
+	switch eventType := evt.EvtType.Name; eventType {
+	case "UnitInit":
+		event := events.UnitInit{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal UnitInit event: %v", err)
+		}
+
+		if event.UnitTypeName == commandCenter {
+			mech.positions[unitTag(event.UnitTagIndex, event.UnitTagRecycle)] = event.WithPosition
+		}
+	case "UnitBorn":
+		event := events.UnitBorn{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal UnitBorn event: %v", err)
+		}
+
+		switch event.UnitTypeName {
+		case commandCenter:
+			mech.positions[unitTag(event.UnitTagIndex, event.UnitTagRecycle)] = event.WithPosition
+		case mule:
+			mech.addMULE(evt.Loop(), event)
+		}
+	case "UnitTypeChange":
+		event := events.UnitTypeChange{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal UnitTypeChange event: %v", err)
+		}
+
+		tag := unitTag(event.UnitTagIndex, event.UnitTagRecycle)
+		unit, ok := sim.IngameUnits[tag]
+		if event.UnitTypeName != orbitalCommand || !ok {
+			return nil
+		}
+		// Orbital commands lifting off and landing change their type
+		// as well.
+		if _, known := mech.orbitals[tag]; known {
+			return nil
+		}
+		player, ok := mech.Players[unit.OwnerID]
+		if !ok {
+			return nil
+		}
+
+		position := mech.positions[tag]
+		orbital := Orbital{Since: evt.Loop(), tag: tag, x: position.X, y: position.Y}
+		mech.orbitals[tag] = &orbital
+		player.Orbitals = append(player.Orbitals, &orbital)
+	case "UnitDied":
+		event := events.UnitDied{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal UnitDied event: %v", err)
+		}
+
+		tag := unitTag(event.UnitTagIndex, event.UnitTagRecycle)
+		if townhall, ok := mech.townhalls[tag]; ok {
+			townhall.diedAt = evt.Loop()
+			// Tags are recycled
+			delete(mech.townhalls, tag)
+		}
+		delete(mech.nexuses, tag)
+		if orbital, ok := mech.orbitals[tag]; ok {
+			orbital.lost = true
+			delete(mech.orbitals, tag)
+		}
+		delete(mech.lastCasts, tag)
+		delete(mech.positions, tag)
+	}
+
+	return nil
+}
+
+func (mech *Mechanics) Finish(sim *Simulator) error {
+	mech.applyCommands(sim, mech.Ticks+1)
+
+	for _, player := range mech.Players {
+		for _, townhall := range player.Townhalls {
+			end := mech.Ticks
+			if townhall.diedAt > 0 && townhall.diedAt < end {
+				end = townhall.diedAt
+			}
+
+			townhall.ActiveLoops = end - townhall.FirstInject
+			// Larvae still spawning at the end do not count.
+			if townhall.injectedUntil > end {
+				townhall.InjectedLoops -= townhall.injectedUntil - end
+			}
+			if townhall.InjectedLoops < 0 {
+				townhall.InjectedLoops = 0
+			}
+		}
+	}
+
+	return nil
+}
+
+// Decode commands using one of the abilities used by race mechanics.
+func (mech *Mechanics) decodeCommands() ([]abilityCommand, error) {
+	commands := make([]abilityCommand, 0)
+	abilities, err := mech.Replay.mechanicsAbilities()
+	if err != nil {
+		return commands, err
+	}
+
+	names := make(map[events.CmdAbil]string)
+	for name, ability := range abilities {
+		names[ability.Abil] = name
+	}
+
+	for _, command := range mech.Replay.playerCommands() {
+		if command.Abil == nil || command.Smart {
+			continue
+		}
+		name, ok := names[*command.Abil]
+		if !ok {
+			continue
+		}
+
+		commands = append(commands, abilityCommand{
+			Loop:      command.Loop,
+			PlayerID:  command.PlayerID,
+			Ability:   name,
+			TargetTag: command.TargetTag,
+			Selected:  command.Selected,
+		})
+	}
+
+	return commands, nil
+}
+
+// Apply all commands issued before the given loop, and within the analysed
+// range.
+func (mech *Mechanics) applyCommands(sim *Simulator, loop int64) {
+	for mech.nextCommand < len(mech.commands) {
+		command := mech.commands[mech.nextCommand]
+		if command.Loop >= loop || command.Loop > mech.Ticks {
+			return
+		}
+		mech.nextCommand += 1
+
+		player, ok := mech.Players[command.PlayerID]
+		if !ok {
+			continue
+		}
+
+		switch command.Ability {
+		case units.SpawnLarva:
+			mech.addInject(sim, player, command)
+		case units.ChronoBoost:
+			mech.addChronoboost(sim, player, command)
+		case units.ScannerSweep:
+			player.Scans += 1
+			if tag, ok := mech.selectedCaster(sim, command, orbitalCommand); ok {
+				if orbital, ok := mech.orbitals[tag]; ok {
+					orbital.Scans += 1
+				}
+			}
+		}
+	}
+}
+
+func (mech *Mechanics) addChronoboost(sim *Simulator, player *PlayerMechanics, command abilityCommand) {
+	target, ok := sim.IngameUnits[command.TargetTag]
+	if !ok || target.OwnerID != command.PlayerID {
+		return
+	}
+
+	name := target.Name
+	if building, ok := mech.Replay.Catalog().Buildings[target.Name]; ok {
+		name = building.Name
+	}
+	player.Chronoboosts += 1
+	player.ChronoboostTargets[name] += 1
+
+	tag, ok := mech.selectedCaster(sim, command, nexus)
+	if !ok {
+		return
+	}
+	caster, ok := mech.nexuses[tag]
+	if !ok {
+		caster = &Nexus{FirstChronoboost: command.Loop, Targets: make(map[string]int)}
+		mech.nexuses[tag] = caster
+		player.Nexuses = append(player.Nexuses, caster)
+	}
+	caster.Chronoboosts += 1
+	caster.Targets[name] += 1
+}
+
+// Return the tag of the selected unit of the given ingame name which cast an
+// ability least recently, and remember it cast one now. Units which never
+// did come first, in the order they were selected.
+func (mech *Mechanics) selectedCaster(sim *Simulator, command abilityCommand, name string) (int64, bool) {
+	caster := int64(0)
+	found := false
+	for _, tag := range command.Selected {
+		unit, ok := sim.IngameUnits[tag]
+		if !ok || unit.OwnerID != command.PlayerID || unit.Name != name {
+			continue
+		}
+		if !found || mech.lastCastOf(tag) < mech.lastCastOf(caster) {
+			caster = tag
+			found = true
+		}
+	}
+
+	if found {
+		mech.lastCasts[caster] = command.Loop
+	}
+
+	return caster, found
+}
+
+// Loop at which the unit last cast an ability, -1 if it never did.
+func (mech *Mechanics) lastCastOf(tag int64) int64 {
+	if loop, ok := mech.lastCasts[tag]; ok {
+		return loop
+	}
+
+	return -1
+}
+
+func (mech *Mechanics) addInject(sim *Simulator, player *PlayerMechanics, command abilityCommand) {
+	target, ok := sim.IngameUnits[command.TargetTag]
+	if !ok || target.OwnerID != command.PlayerID || !injectableTownhalls[target.Name] {
+		return
+	}
+
+	townhall, ok := mech.townhalls[command.TargetTag]
+	if !ok {
+		townhall = &InjectedTownhall{FirstInject: command.Loop}
+		mech.townhalls[command.TargetTag] = townhall
+		player.Townhalls = append(player.Townhalls, townhall)
+	}
+
+	townhall.Name = mech.Replay.Catalog().Buildings[target.Name].Name
+	townhall.Injects += 1
+
+	// Injects into a townhall which is injected already are queued.
+	start := command.Loop
+	if townhall.injectedUntil > start {
+		start = townhall.injectedUntil
+	}
+	townhall.injectedUntil = start + mech.injectDuration
+	townhall.InjectedLoops += mech.injectDuration
+}
+
+func (mech *Mechanics) addMULE(loop int64, event events.UnitBorn) {
+	player, ok := mech.Players[event.UpkeepPlayerID]
+	if !ok {
+		return
+	}
+	player.MULEs += 1
+
+	// Only recent replays tell which orbital command called it down.
+	if event.CreatorUnitTagIndex != nil && event.CreatorUnitTagRecycle != nil {
+		tag := unitTag(*event.CreatorUnitTagIndex, *event.CreatorUnitTagRecycle)
+		if orbital, ok := mech.orbitals[tag]; ok {
+			orbital.MULEs += 1
+			mech.lastCasts[tag] = loop
+			return
+		}
+	}
+
+	var closest *Orbital
+	for _, orbital := range player.Orbitals {
+		if orbital.lost {
+			continue
+		}
+		if closest == nil || distanceSquared(orbital, event.WithPosition) < distanceSquared(closest, event.WithPosition) {
+			closest = orbital
+		}
+	}
+	if closest != nil {
+		closest.MULEs += 1
+		mech.lastCasts[closest.tag] = loop
+	}
+}
+
+func distanceSquared(orbital *Orbital, position events.WithPosition) int64 {
+	dx := orbital.x - position.X
+	dy := orbital.y - position.Y
+
+	return dx*dx + dy*dy
+}
@@ -0,0 +1,68 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+)
+
+type MessageKind int
+
+const (
+	MessageChat MessageKind = iota
+	MessagePing
+)
+
+type Message struct {
+	Loop int64
+	Kind MessageKind
+
+	UserID int64
+	// Name of the sender, including their clan tag
+	Sender string
+	// One of the `events.Recipient*` constants
+	Recipient int64
+	// Empty for pings
+	Text string
+}
+
+// Whether only the sender's allies (or fellow observers) could see the
+// message.
+func (msg *Message) Private() bool {
+	return msg.Recipient != events.RecipientAll
+}
+
+// Return all chat messages and pings, in chronological order.
+func (replay *Replay) Messages() ([]Message, error) {
+	messages := make([]Message, 0)
+
+	for _, evt := range replay.Rep.MessageEvts {
+		msg := Message{Loop: evt.Loop(), UserID: evt.UserID()}
+
+		switch eventType := evt.EvtType.Name; eventType {
+		case "Chat":
+			event := events.Chat{}
+			if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+				return messages, fmt.Errorf("Unable to unmarshal Chat event: %v", err)
+			}
+			msg.Kind = MessageChat
+			msg.Recipient = event.Recipient
+			msg.Text = event.String
+		case "Ping":
+			event := events.Ping{}
+			if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+				return messages, fmt.Errorf("Unable to unmarshal Ping event: %v", err)
+			}
+			msg.Kind = MessagePing
+			msg.Recipient = event.Recipient
+		default:
+			// Eg loading progress, server pings
+			continue
+		}
+
+		msg.Sender = replay.UserName(msg.UserID)
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,179 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/dragaera/probius/internal/sc2replay/units"
+)
+
+// Maximum amount of loops between an egg dying and the units hatching from it
+// being born, as well as their maximum distance.
+const hatchLoopTolerance int64 = 16
+const hatchDistanceTolerance int64 = 3
+
+// Identifies a unit's stint as an egg. Larvae can turn into eggs more than
+// once, if morphing is cancelled.
+type eggKey struct {
+	Tag       int64
+	StartLoop int64
+}
+
+// Egg which reverted to a larva and died, awaiting units being born in its
+// place.
+type hatchingEgg struct {
+	key     eggKey
+	ownerID int64
+	x       int64
+	y       int64
+	loop    int64
+	// Ingame name of the units born so far, and how many more of them are
+	// expected.
+	target    string
+	remaining int
+}
+
+// Whether the unit being born hatched from this egg. If so, it is claimed,
+// so it is not attributed to another egg.
+func (egg *hatchingEgg) claim(loop int64, event events.UnitBorn) bool {
+	if egg.ownerID != event.UpkeepPlayerID || loop-egg.loop > hatchLoopTolerance {
+		return false
+	}
+	if abs(event.X-egg.x) > hatchDistanceTolerance || abs(event.Y-egg.y) > hatchDistanceTolerance {
+		return false
+	}
+
+	switch {
+	case len(egg.target) == 0:
+		egg.target = event.UnitTypeName
+		egg.remaining = units.UnitsPerEgg(event.UnitTypeName) - 1
+		return true
+	case egg.target == event.UnitTypeName && egg.remaining > 0:
+		egg.remaining -= 1
+		return true
+	default:
+		return false
+	}
+}
+
+// Return what each egg in the replay hatched into, by ingame name. Eggs which
+// were killed or cancelled are missing.
+func (replay *Replay) eggHatches() map[eggKey]string {
+	if replay.hatches == nil {
+		replay.hatches = findEggHatches(replay)
+	}
+
+	return replay.hatches
+}
+
+// The replay only tells us which unit an egg turned into once it hatches.
+// Depending on the game version, the egg either turns into the unit, or
+// reverts to a larva and dies, with the units being born in its place.
+func findEggHatches(replay *Replay) map[eggKey]string {
+	hatches := make(map[eggKey]string)
+
+	owners := make(map[int64]int64)
+	// Eggs by tag
+	eggs := make(map[int64]eggKey)
+	// Eggs which reverted to larvae in the current loop, by tag. Unless
+	// they die in the same loop, they were cancelled.
+	reverted := make(map[int64]eggKey)
+	revertedLoop := int64(-1)
+	hatching := make([]*hatchingEgg, 0)
+
+	for _, evt := range replay.Rep.TrackerEvts.Evts {
+		loop := evt.Loop()
+		if loop != revertedLoop && len(reverted) > 0 {
+			reverted = make(map[int64]eggKey)
+		}
+
+		switch evt.EvtType.Name {
+		case "UnitBorn":
+			event := events.UnitBorn{}
+			if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+				continue
+			}
+			owners[unitTag(event.UnitTagIndex, event.UnitTagRecycle)] = event.UpkeepPlayerID
+
+			claimed := false
+			remaining := hatching[:0]
+			for _, egg := range hatching {
+				if !claimed && egg.claim(loop, event) {
+					hatches[egg.key] = egg.target
+					claimed = true
+				}
+				if loop-egg.loop <= hatchLoopTolerance {
+					remaining = append(remaining, egg)
+				}
+			}
+			hatching = remaining
+		case "UnitTypeChange":
+			event := events.UnitTypeChange{}
+			if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+				continue
+			}
+			tag := unitTag(event.UnitTagIndex, event.UnitTagRecycle)
+
+			key, isEgg := eggs[tag]
+			switch {
+			case event.UnitTypeName == units.Egg:
+				eggs[tag] = eggKey{Tag: tag, StartLoop: loop}
+			case isEgg && event.UnitTypeName == units.Larva:
+				delete(eggs, tag)
+				reverted[tag] = key
+				revertedLoop = loop
+			case isEgg:
+				delete(eggs, tag)
+				hatches[key] = event.UnitTypeName
+			}
+		case "UnitDied":
+			event := events.UnitDied{}
+			if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+				continue
+			}
+			tag := unitTag(event.UnitTagIndex, event.UnitTagRecycle)
+			delete(eggs, tag)
+
+			if key, ok := reverted[tag]; ok {
+				hatching = append(hatching, &hatchingEgg{
+					key:     key,
+					ownerID: owners[tag],
+					x:       event.X,
+					y:       event.Y,
+					loop:    loop,
+				})
+			}
+		}
+	}
+
+	return hatches
+}
+
+// Return static information about what an egg or cocoon is morphing into,
+// with supply and cost covering all units hatching from it.
+func morphingUnit(catalog *units.Catalog, unit IngameUnit) (units.Unit, bool) {
+	if len(unit.MorphTarget) == 0 {
+		return units.Unit{}, false
+	}
+
+	target, ok := catalog.Units[unit.MorphTarget]
+	if !ok {
+		return target, false
+	}
+
+	cost := units.Cost{}
+	for i := 0; i < unit.MorphCount; i++ {
+		cost = cost.Add(target.Cost)
+	}
+	target.Cost = cost
+	target.Supply *= float64(unit.MorphCount)
+
+	return target, true
+}
+
+func abs(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
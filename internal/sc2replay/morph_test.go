@@ -0,0 +1,61 @@
+package sc2replay
+
+import (
+	"github.com/dragaera/probius/internal/sc2replay/units"
+	"testing"
+)
+
+func TestMorphingUnit(t *testing.T) {
+	catalog := units.DefaultCatalog()
+	zergling := catalog.Units["Zergling"]
+	baneling := catalog.Units["Baneling"]
+
+	tests := []struct {
+		name       string
+		unit       IngameUnit
+		wantOK     bool
+		wantName   string
+		wantSupply float64
+		wantCost   units.Cost
+	}{
+		{
+			name: "not morphing",
+			unit: IngameUnit{Name: "Drone"},
+		},
+		{
+			name: "unknown target",
+			unit: IngameUnit{Name: units.Egg, MorphTarget: "Unknown", MorphCount: 1},
+		},
+		{
+			name:       "single unit",
+			unit:       IngameUnit{Name: "BanelingCocoon", MorphTarget: "Baneling", MorphCount: 1},
+			wantOK:     true,
+			wantName:   baneling.Name,
+			wantSupply: baneling.Supply,
+			wantCost:   baneling.Cost,
+		},
+		{
+			name:       "multiple units per egg",
+			unit:       IngameUnit{Name: units.Egg, MorphTarget: "Zergling", MorphCount: 2},
+			wantOK:     true,
+			wantName:   zergling.Name,
+			wantSupply: 2 * zergling.Supply,
+			wantCost:   zergling.Cost.Add(zergling.Cost),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := morphingUnit(catalog, test.unit)
+			if ok != test.wantOK {
+				t.Fatalf("morphingUnit() ok = %v, want %v", ok, test.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got.Name != test.wantName || got.Supply != test.wantSupply || got.Cost != test.wantCost {
+				t.Errorf("morphingUnit() = %v (%v supply, %v), want %v (%v supply, %v)", got.Name, got.Supply, got.Cost, test.wantName, test.wantSupply, test.wantCost)
+			}
+		})
+	}
+}
@@ -4,12 +4,33 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/dragaera/probius/internal/sc2replay/units"
 	"github.com/icza/s2prot/rep"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Version of the analyses. Bump this whenever they change in a way which
+// affects their results, to invalidate cached results.
+const AnalysisVersion int = 9
+
 type Replay struct {
 	Rep *rep.Rep
+	// Diagnostics of processing the replay outside of a simulator, eg when
+	// determining its owner.
+	Diagnostics Diagnostics
+
+	// What eggs hatched into, determined on first use. See `eggHatches`.
+	hatches map[eggKey]string
+	// Commands of human players, determined on first use. See
+	// `playerCommands`.
+	commands []playerCommand
+	// Abilities used by race mechanics, determined on first use. See
+	// `mechanicsAbilities`.
+	abilities map[string]mechanicsAbility
 }
 
 func FromFile(path string) (Replay, error) {
@@ -27,6 +48,12 @@ func (replay *Replay) Close() error {
 	return replay.Rep.Close()
 }
 
+// Return the static information about units, buildings and upgrades which
+// matches the game version of the replay.
+func (replay *Replay) Catalog() *units.Catalog {
+	return units.CatalogFor(replay.Rep.Header.BaseBuild())
+}
+
 func (replay *Replay) TicksPerSecond() (float64, error) {
 	switch replay.Rep.Details.GameSpeed() {
 	case rep.GameSpeedSlower:
@@ -57,6 +84,164 @@ func (replay *Replay) TicksUntilSeconds(seconds float64) (int64, error) {
 	return int64(math.Round(ticksPerSecond * seconds)), nil
 }
 
+// Return an identifier of the game the replay is of. Replays of the same game
+// saved by different players share the same identifier.
+func (replay *Replay) GameID() string {
+	return fmt.Sprintf(
+		"%d-%d",
+		replay.Rep.Details.Time().Unix(),
+		replay.Rep.InitData.LobbyState.RandomSeed(),
+	)
+}
+
+// Return the ingame duration after the given amount of ticks.
+func (replay *Replay) DurationAt(ticks int64) (time.Duration, error) {
+	ticksPerSecond, err := replay.TicksPerSecond()
+	if err != nil {
+		return 0, err
+	}
+
+	seconds := float64(ticks) / ticksPerSecond
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// Return the name of the player with the given player ID, prefixed by their
+// clan tag if they have one.
+func (replay *Replay) PlayerName(playerID int64) (string, error) {
+	player, ok := replay.Rep.TrackerEvts.PIDPlayerDescMap[playerID]
+	if !ok {
+		return "", fmt.Errorf("Unable to find player with ID %d", playerID)
+	}
+
+	if int(player.UserID) >= len(replay.Rep.InitData.UserInitDatas) {
+		return "", fmt.Errorf("Unable to find user with ID %d", player.UserID)
+	}
+
+	return replay.UserName(player.UserID), nil
+}
+
+// Return the name of the user with the given user ID, prefixed by their clan
+// tag if they have one. Users include observers, but not AI players.
+func (replay *Replay) UserName(userID int64) string {
+	if userID < 0 || int(userID) >= len(replay.Rep.InitData.UserInitDatas) {
+		return fmt.Sprintf("Unknown user %d", userID)
+	}
+
+	name := ""
+	user := replay.Rep.InitData.UserInitDatas[userID]
+	if len(user.ClanTag()) > 0 {
+		name += fmt.Sprintf("<%s> ", user.ClanTag())
+	}
+	name += user.Name()
+
+	return name
+}
+
+// Return the player ID of the player with the given name. The comparison is
+// case-insensitive and ignores clan tags.
+func (replay *Replay) PlayerIDByName(name string) (int64, error) {
+	for playerID, player := range replay.Rep.TrackerEvts.PIDPlayerDescMap {
+		if int(player.UserID) >= len(replay.Rep.InitData.UserInitDatas) {
+			continue
+		}
+
+		user := replay.Rep.InitData.UserInitDatas[player.UserID]
+		if strings.EqualFold(user.Name(), name) {
+			return playerID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("No player with name '%s' found", name)
+}
+
+// Return the IDs of all players, in ascending order.
+func (replay *Replay) PlayerIDs() []int64 {
+	ids := make([]int64, 0, len(replay.Rep.TrackerEvts.PIDPlayerDescMap))
+	for playerID := range replay.Rep.TrackerEvts.PIDPlayerDescMap {
+		ids = append(ids, playerID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+// Return the player IDs of human players by their user ID. Game events are
+// associated with users, not players. AI players have no user of their own,
+// and thus no game events.
+func (replay *Replay) humanPlayerIDs() map[int64]int64 {
+	playerIDs := make(map[int64]int64)
+	players := len(replay.Rep.Details.Players())
+	for playerID, player := range replay.Rep.TrackerEvts.PIDPlayerDescMap {
+		// Some replays describe the neutral and hostile players as
+		// well, which share the user and slot of the first player.
+		if playerID < 1 || int(playerID) > players {
+			continue
+		}
+		if int(player.SlotID) >= len(replay.Rep.InitData.LobbyState.Slots) {
+			continue
+		}
+		slot := replay.Rep.InitData.LobbyState.Slots[player.SlotID]
+		if slot.Control() == rep.ControlHuman {
+			playerIDs[player.UserID] = playerID
+		}
+	}
+
+	return playerIDs
+}
+
+// Return the details of the player with the given player ID. They are matched
+// by lobby slot where the replay records it, and by order otherwise.
+func (replay *Replay) detailsPlayer(playerID int64) (*rep.Player, bool) {
+	players := replay.Rep.Details.Players()
+	// Neutral and hostile players have no details, but share the slot of
+	// the first player.
+	if playerID < 1 || int(playerID) > len(players) {
+		return nil, false
+	}
+
+	if desc, ok := replay.Rep.TrackerEvts.PIDPlayerDescMap[playerID]; ok {
+		for i := range players {
+			if players[i].Value("workingSetSlotId") != nil && players[i].WorkingSetSlotID() == desc.SlotID {
+				return &players[i], true
+			}
+		}
+	}
+
+	return &players[playerID-1], true
+}
+
+// Return the IDs of players matching the given selector, which is one of:
+// - `all`, matching all players
+// - A (1-based) slot number, as in the lobby
+// - A player name, as per `PlayerIDByName`
+func (replay *Replay) SelectPlayers(selector string) ([]int64, error) {
+	if strings.EqualFold(selector, "all") {
+		return replay.PlayerIDs(), nil
+	}
+
+	if slot, err := strconv.Atoi(selector); err == nil {
+		for playerID, player := range replay.Rep.TrackerEvts.PIDPlayerDescMap {
+			if player.SlotID+1 == int64(slot) {
+				return []int64{playerID}, nil
+			}
+		}
+
+		// Could still be a player named eg '1337'
+		if playerID, err := replay.PlayerIDByName(selector); err == nil {
+			return []int64{playerID}, nil
+		}
+
+		return nil, fmt.Errorf("No player in slot %d found", slot)
+	}
+
+	playerID, err := replay.PlayerIDByName(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return []int64{playerID}, nil
+}
+
 // Return the *User ID* of the replay's owner.
 //
 // Mind that this is NOT the Player ID, but rather a separate identifier.
@@ -114,12 +299,17 @@ func (replay *Replay) OwnerPlayerID() (int64, error) {
 	// We're left with multiple possible players owned by the same user.
 	// We'll now narrow it down to only *human* players.
 
-	fmt.Println("Multiple possible replay owner player IDs detected, checking which players are human.")
+	replay.Diagnostics.Addf(
+		SeverityInfo,
+		"User %d controls %d players, assuming the human one to be the replay's owner",
+		userID,
+		len(possiblePlayers),
+	)
 	possibleHumanPlayers := make([]*(rep.PlayerDesc), 0)
 	for _, desc := range possiblePlayers {
 		if int(desc.SlotID) > len(replay.Rep.InitData.LobbyState.Slots)-1 {
 			// Shouldn't ever happen, as slots 0-15 are always populated (even if empty), but who knows...
-			fmt.Printf("Player %d has no slot assigned, skipping\n", desc.PlayerID)
+			replay.Diagnostics.Addf(SeverityWarning, "Player %d has no lobby slot assigned, so cannot be the replay's owner", desc.PlayerID)
 			continue
 		}
 		slot := replay.Rep.InitData.LobbyState.Slots[desc.SlotID]
@@ -1,12 +1,9 @@
 package sc2replay
 
 import (
-	"encoding/json"
-	"fmt"
-	"github.com/dragaera/probius/internal/sc2replay/events"
 	"github.com/dragaera/probius/internal/sc2replay/units"
-	"github.com/icza/s2prot"
 	"math"
+	"sort"
 )
 
 type IngameUnit struct {
@@ -16,6 +13,19 @@ type IngameUnit struct {
 	// The ID of the one towards whose *upkeep* it counts. Ie we don't care
 	// about neuralled units etc.
 	OwnerID int64
+	// The ID of the one controlling it, which differs from the owner eg
+	// for neuralled units.
+	ControllerID int64
+	// False while a building is being constructed, a unit is warping
+	// in, or an egg or cocoon is morphing.
+	Completed bool
+	// Loop at which construction, warping in or morphing started, or at
+	// which the unit was born if it was never in production.
+	StartLoop int64
+	// Ingame name of what an egg or cocoon is morphing into, and how many
+	// of them. Empty if it is neither, or if it is not known.
+	MorphTarget string
+	MorphCount  int
 }
 
 type IngameUpgrade struct {
@@ -29,6 +39,8 @@ type Report struct {
 	PlayerID   int64
 	PlayerName string
 	Replay     *Replay
+	// Amount of ticks up until which the report was generated
+	Ticks int64
 
 	// Map containing everything the game considers a unit. This also
 	// includes buildings, mineral patches etc. This also contains units
@@ -48,217 +60,205 @@ type Report struct {
 	// ID, and only for those for which specific information is available.
 	Upgrades []units.Upgrade
 
-	// Count of units by ingame name
+	// Sorted ingame names of units, buildings and upgrades belonging to
+	// the specified player ID, which are missing from the catalog. These
+	// are not accounted for in counts, supply or values.
+	UnknownUnits    []string
+	UnknownUpgrades []string
+
+	// Count of completed units and buildings by human-readable name
 	UnitCount     map[string]int
 	BuildingCount map[string]int
 
+	// Units and buildings which are still in production, ordered by
+	// name and progress.
+	UnitsInProduction     []Production
+	BuildingsInProduction []Production
+	// Units in eggs and cocoons, ordered by name and progress. Their
+	// supply is part of `Supply` already, as in-game.
+	Morphing []Production
+
 	// Critter stats
 	CritterStats map[units.Critter]CritterStat
-
-	// Supply. As there are units with 0.5 supply, this is a float. Use
-	// `Report.IngameSupply()` for the integer (rounded) supply as shown in-game.
+	// Critters killed by any player, in chronological order
+	CritterKills []CritterKill
+
+	// Units the specified player gained or lost control or ownership of,
+	// in chronological order
+	OwnershipTransfers []OwnershipTransfer
+
+	// Actions per minute, and effective actions per minute, averaged
+	// until the report's timestamp.
+	APM float64
+	EPM float64
+
+	// Most recent economy sample of the specified player.
+	Economy EconomySample
+
+	// Supply of completed units as well as those in production. As there
+	// are units with 0.5 supply, this is a float. Use
+	// `Report.IngameSupply()` for the integer (rounded) supply.
+	//
+	// This is the one matching the in-game supply counter, as supply is
+	// taken up as soon as production starts. Mind that units trained in
+	// production buildings are not known until they are done, as the
+	// replay contains no events about them being queued, so they are
+	// missing until then. `Economy.Supply` is the exact in-game value,
+	// but only sampled every ten seconds.
 	Supply float64
+	// Supply of completed units only.
+	CompletedSupply float64
+
+	// Resources invested into units other than workers and supply, into
+	// buildings and upgrades other than economic ones, and into workers,
+	// townhalls, gas and supply respectively. See `units.Economy`.
+	ArmyValue    units.Cost
+	TechValue    units.Cost
+	EconomyValue units.Cost
+
+	// Problems encountered while processing the replay up until the
+	// report's timestamp, as well as while generating the report.
+	Diagnostics Diagnostics
 }
 
-type CritterStat struct {
-	Total int
-	Alive int
+type Production struct {
+	// Human-readable name
+	Name string
+	// Amount of units, eg two zerglings hatching from an egg
+	Count int
+	// Loop at which production started
+	StartLoop int64
+	// Between 0 and 1, estimated based on the build time. Zero if the
+	// build time is unknown.
+	Progress float64
 }
 
-// Call this to generate the report.
-func (rep *Report) At(ticks int64) {
-	rep.IngameUnits = make(map[int64]IngameUnit)
-	rep.IngameUpgrades = make([]IngameUpgrade, 0)
-	rep.CritterStats = make(map[units.Critter]CritterStat)
-
-	rep.calculateMetaInformation()
-
-	for _, evt := range rep.Replay.Rep.TrackerEvts.Evts {
-		// We handle this here to allow an early exit
-		if evt.Loop() > int64(ticks) {
-			fmt.Printf("Reached %d ticks, stopping\n", ticks)
-			break
-		}
-		if err := rep.handleEvent(evt); err != nil {
-			fmt.Printf("Error while handling event: %v\n", err)
-			fmt.Printf("%+v\n", evt)
-		}
-	}
-
-	// Remove units belonging to other players
-	rep.prune()
-	// Enrich with static information (supply, human-readable name etc)
-	rep.enrich()
-
-	rep.calculateUnitCount()
-	rep.calculateBuildingCount()
-	rep.calculateSupply()
+// A unit changing hands. Units which are neural parasited change control,
+// but not ownership, whereas units given to allies change both.
+type OwnershipTransfer struct {
+	Loop int64
+	// Ingame name
+	Unit             string
+	FromOwnerID      int64
+	ToOwnerID        int64
+	FromControllerID int64
+	ToControllerID   int64
 }
 
-// Return rounded supply as shown in-game
-func (rep *Report) IngameSupply() int {
-	return int(math.Round(rep.Supply))
+// Whether the given player gained or lost control or ownership.
+func (transfer *OwnershipTransfer) Involves(playerID int64) bool {
+	return transfer.FromOwnerID == playerID ||
+		transfer.ToOwnerID == playerID ||
+		transfer.FromControllerID == playerID ||
+		transfer.ToControllerID == playerID
 }
 
-func (rep *Report) handleEvent(evt s2prot.Event) error {
-	switch eventType := evt.EvtType.Name; eventType {
-	case "UnitBorn":
-		if err := rep.trackUnitBorn(evt); err != nil {
-			return err
-		}
-	case "UnitInit":
-		if err := rep.trackUnitInit(evt); err != nil {
-			return err
-		}
-	case "UnitDone":
-		// UnitDone is for eg:
-		// - A unit finishing warpin
-		// - A building finishing morphing
-		// As we already add units to the unit list when they start
-		// building, we have no need for this.
-	case "UnitTypeChange":
-		// UnitTypeChange is for eg:
-		// - Buildings transforming (eg gateway => warpgate)
-		// - Creep tumors burrowing
-		// - Larva transforming into eggs
-		// - Hellions transforming into Hellbats
-		if err := rep.trackUnitTypeChange(evt); err != nil {
-			return err
-		}
-	case "UnitDied":
-		if err := rep.trackUnitDied(evt); err != nil {
-			return err
-		}
-	case "Upgrade":
-		if err := rep.trackUpgrade(evt); err != nil {
-			return err
-		}
-	default:
-		// fmt.Printf("[%d]: %s by %d\n", evt.Loop(), eventType, evt.UserID())
-	}
-
-	return nil
+type CritterStat struct {
+	Total int
+	Alive int
 }
 
-func (rep *Report) trackUnitBorn(evt s2prot.Event) error {
-	event := events.UnitBorn{}
-	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
-		return fmt.Errorf("Unable to unmarshal UnitBorn event: %v", err)
-	}
-
-	if err := rep.addUnit(event.UnitTagIndex, event.UnitTagRecycle, event.UnitTypeName, event.UpkeepPlayerID); err != nil {
-		return err
-	}
-
-	return nil
+type CritterKill struct {
+	Loop int64
+	// Unit tag of the critter
+	Tag     int64
+	Critter units.Critter
+	// Zero if the critter was not killed by a player
+	KillerPlayerID int64
+	// Human-readable name if known, ingame name otherwise. Empty if the
+	// killing unit is unknown.
+	KillerUnit string
 }
 
-func (rep *Report) trackUnitInit(evt s2prot.Event) error {
-	event := events.UnitInit{}
-	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
-		return fmt.Errorf("Unable to unmarshal UnitInit event: %v", err)
-	}
-
-	if err := rep.addUnit(event.UnitTagIndex, event.UnitTagRecycle, event.UnitTypeName, event.UpkeepPlayerID); err != nil {
-		return err
-	}
-
-	return nil
+// Call this to generate the report.
+func (rep *Report) At(ticks int64) {
+	*rep = ReportsAt(rep.Replay, []int64{rep.PlayerID}, []int64{ticks})[0][0]
 }
 
-func (rep *Report) trackUnitTypeChange(evt s2prot.Event) error {
-	event := events.UnitTypeChange{}
-	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
-		return fmt.Errorf("Unable to unmarshal UnitTypeChange event: %v", err)
-	}
-
-	if err := rep.replaceUnit(event.UnitTagIndex, event.UnitTagRecycle, event.UnitTypeName); err != nil {
-		return err
+// Generate reports of each of the given players at each of the given ticks,
+// which must be in ascending order. Reports are indexed by player, then by
+// tick. Tracker and game events are processed only once for all players, with
+// a snapshot being taken whenever one of the ticks is reached. Errors
+// preventing parts of the replay from being processed are added to the
+// reports' diagnostics.
+func ReportsAt(replay *Replay, playerIDs []int64, ticks []int64) [][]Report {
+	reports := make([][]Report, len(playerIDs))
+	for i := range reports {
+		reports[i] = make([]Report, 0, len(ticks))
 	}
 
-	return nil
-}
+	apm := APM{Replay: replay}
+	samples, apmErr := apm.CalculateAt(ticks)
 
-func (rep *Report) trackUnitDied(evt s2prot.Event) error {
-	event := events.UnitDied{}
-	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
-		return fmt.Errorf("Unable to unmarshal UnitDied event: %v", err)
-	}
+	sim := NewSimulator(replay)
+	var simErr error
+	for i, t := range ticks {
+		if simErr == nil {
+			simErr = sim.AdvanceTo(t)
+		}
 
-	if err := rep.removeUnit(event.UnitTagIndex, event.UnitTagRecycle); err != nil {
-		return err
+		for j, playerID := range playerIDs {
+			var actions *PlayerActions
+			if apmErr == nil {
+				actions = samples[i][playerID]
+			}
+
+			// Reports prune the snapshot, so each needs its own.
+			report := NewReport(replay, playerID, t, sim.Snapshot(), actions)
+			if simErr != nil {
+				report.Diagnostics.Addf(SeverityError, "Unable to process tracker events until loop %d: %v", t, simErr)
+			}
+			if apmErr != nil {
+				report.Diagnostics.Addf(SeverityError, "Unable to calculate APM: %v", apmErr)
+			}
+			reports[j] = append(reports[j], report)
+		}
 	}
 
-	return nil
+	return reports
 }
 
-func (rep *Report) addUnit(index int64, recycle int64, name string, ownerID int64) error {
-	tag := unitTag(index, recycle)
-
-	if existing, ok := rep.IngameUnits[tag]; ok {
-		// Unit with given tag exists already => That's a mistake
-		return fmt.Errorf("Unit tag %d reused. Existing: %s, new: %s", tag, existing, name)
+// Create a report of the given player from a snapshot of the game's state and
+// the player's actions up until then, which may be nil if unknown.
+func NewReport(replay *Replay, playerID int64, ticks int64, snapshot Snapshot, actions *PlayerActions) Report {
+	rep := Report{
+		PlayerID:           playerID,
+		Replay:             replay,
+		Ticks:              ticks,
+		IngameUnits:        snapshot.IngameUnits,
+		IngameUpgrades:     snapshot.IngameUpgrades,
+		CritterStats:       snapshot.CritterStats,
+		CritterKills:       snapshot.CritterKills,
+		OwnershipTransfers: snapshot.OwnershipTransfers,
+		Economy:            snapshot.Economy[playerID],
+		Diagnostics:        Diagnostics{Entries: snapshot.Diagnostics},
 	}
-	rep.IngameUnits[tag] = IngameUnit{Index: index, Recycle: recycle, Name: name, OwnerID: ownerID}
-
-	// Special treatment for critters :)
-	if critter, ok := units.Critters[name]; ok {
-		oldStats := rep.CritterStats[critter]
-		newStats := CritterStat{Total: oldStats.Total + 1, Alive: oldStats.Alive + 1}
-		rep.CritterStats[critter] = newStats
-	}
-
-	return nil
-}
-
-func (rep *Report) replaceUnit(index int64, recycle int64, name string) error {
-	tag := unitTag(index, recycle)
-
-	if _, ok := rep.IngameUnits[tag]; !ok {
-		// Trying to replace a nonexistant unit
-		return fmt.Errorf("Tried to replace unit with tag %d but does not exist", tag)
+	if err := rep.calculateMetaInformation(); err != nil {
+		rep.Diagnostics.Addf(SeverityWarning, "Unable to determine name of player %d: %v", playerID, err)
 	}
-	// Cannot change struct fields in maps
-	existing := rep.IngameUnits[tag]
-	existing.Name = name
-	rep.IngameUnits[tag] = existing
-
-	return nil
-}
 
-func (rep *Report) trackUpgrade(evt s2prot.Event) error {
-	event := events.Upgrade{}
-	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
-		return fmt.Errorf("Unable to unmarshal Upgrade event: %v", err)
-	}
+	// Remove units belonging to other players
+	rep.prune()
+	// Enrich with static information (supply, human-readable name etc)
+	rep.enrich()
 
-	rep.IngameUpgrades = append(rep.IngameUpgrades, IngameUpgrade{Name: event.UpgradeTypeName, OwnerID: event.PlayerID})
+	rep.calculateUnitCount()
+	rep.calculateBuildingCount()
+	rep.calculateProduction()
+	rep.calculateSupply()
+	rep.calculateValues()
+	rep.calculateAPM(actions)
 
-	return nil
+	return rep
 }
 
-func (rep *Report) removeUnit(index int64, recycle int64) error {
-	tag := unitTag(index, recycle)
-
-	unit, ok := rep.IngameUnits[tag]
-	if !ok {
-		// Trying to remove a nonexistant unit
-		return fmt.Errorf("Tried to remove unit tag %d but does not exist", tag)
-	}
-
-	// Special treatment for critters :)
-	if critter, ok := units.Critters[unit.Name]; ok {
-		oldStats := rep.CritterStats[critter]
-		newStats := CritterStat{Total: oldStats.Total, Alive: oldStats.Alive - 1}
-		rep.CritterStats[critter] = newStats
-	}
-
-	delete(rep.IngameUnits, tag)
-
-	return nil
+// Return rounded supply as shown in-game
+func (rep *Report) IngameSupply() int {
+	return int(math.Round(rep.Supply))
 }
 
-// Remove all units owned (in terms of supply) other than rep.PlayerID
+// Remove all units owned (in terms of supply) other than rep.PlayerID, as of
+// the report's timestamp, as well as ownership transfers not involving them.
 func (rep *Report) prune() {
 	for tag, unit := range rep.IngameUnits {
 		if unit.OwnerID != rep.PlayerID {
@@ -273,82 +273,216 @@ func (rep *Report) prune() {
 		}
 	}
 	rep.IngameUpgrades = newUpgrades
+
+	newTransfers := make([]OwnershipTransfer, 0)
+	for _, transfer := range rep.OwnershipTransfers {
+		if transfer.Involves(rep.PlayerID) {
+			newTransfers = append(newTransfers, transfer)
+		}
+	}
+	rep.OwnershipTransfers = newTransfers
 }
 
 // Enrich with static per-unit information such as supply and name.
 //
 // Enriched information will be in rep.Units, which will only contain those
-// units for which enriched information is available.
+// units for which enriched information is available. Names of the others are
+// collected in rep.UnknownUnits and rep.UnknownUpgrades, unless the catalog
+// ignores them.
 func (rep *Report) enrich() {
 	rep.Units = make(map[int64]units.Unit)
 	rep.Buildings = make(map[int64]units.Building)
 	rep.Upgrades = make([]units.Upgrade, 0)
+	catalog := rep.Replay.Catalog()
+
+	unknownUnits := make(map[string]bool)
+	unknownUpgrades := make(map[string]bool)
 
 	for tag, unit := range rep.IngameUnits {
-		if enrichedUnit, ok := units.Units[unit.Name]; ok {
+		// Eggs and cocoons count as what they are morphing into
+		if enrichedUnit, ok := morphingUnit(catalog, unit); ok {
 			rep.Units[tag] = enrichedUnit
 			continue
 		}
 
-		if enrichedBuilding, ok := units.Buildings[unit.Name]; ok {
+		if enrichedUnit, ok := catalog.Units[unit.Name]; ok {
+			rep.Units[tag] = enrichedUnit
+			continue
+		}
+
+		if enrichedBuilding, ok := catalog.Buildings[unit.Name]; ok {
 			rep.Buildings[tag] = enrichedBuilding
 			continue
 		}
-		// fmt.Printf("Unknown ingame unit: %v\n", unit.Name)
+
+		if !catalog.Ignores(unit.Name) {
+			unknownUnits[unit.Name] = true
+		}
 	}
 
 	for _, upgrade := range rep.IngameUpgrades {
-		if enrichedUpgrade, ok := units.Upgrades[upgrade.Name]; ok {
+		if enrichedUpgrade, ok := catalog.Upgrades[upgrade.Name]; ok {
 			rep.Upgrades = append(rep.Upgrades, enrichedUpgrade)
 			continue
 		}
-		// fmt.Printf("Unknown upgrade: %v\n", upgrade.Name)
+
+		if !catalog.Ignores(upgrade.Name) {
+			unknownUpgrades[upgrade.Name] = true
+		}
 	}
-}
 
-func (rep *Report) calculateMetaInformation() error {
-	rep.PlayerName = ""
+	rep.UnknownUnits = sortedNames(unknownUnits)
+	rep.UnknownUpgrades = sortedNames(unknownUpgrades)
+}
 
-	player, ok := rep.Replay.Rep.TrackerEvts.PIDPlayerDescMap[rep.PlayerID]
-	if !ok {
-		return fmt.Errorf("Unable to find player with ID %d", rep.PlayerID)
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	user := rep.Replay.Rep.InitData.UserInitDatas[player.UserID]
-	if len(user.ClanTag()) > 0 {
-		rep.PlayerName += fmt.Sprintf("<%s> ", user.ClanTag())
-	}
-	rep.PlayerName += user.Name()
+	return names
+}
+
+func (rep *Report) calculateMetaInformation() error {
+	name, err := rep.Replay.PlayerName(rep.PlayerID)
+	rep.PlayerName = name
 
-	return nil
+	return err
 }
 
 func (rep *Report) calculateUnitCount() {
 	rep.UnitCount = make(map[string]int)
 
-	for _, unit := range rep.Units {
-		rep.UnitCount[unit.Name] += 1
+	for tag, unit := range rep.Units {
+		if rep.IngameUnits[tag].Completed {
+			rep.UnitCount[unit.Name] += 1
+		}
 	}
 }
 
 func (rep *Report) calculateBuildingCount() {
 	rep.BuildingCount = make(map[string]int)
 
-	for _, building := range rep.Buildings {
-		rep.BuildingCount[building.Name] += 1
+	for tag, building := range rep.Buildings {
+		if rep.IngameUnits[tag].Completed {
+			rep.BuildingCount[building.Name] += 1
+		}
+	}
+}
+
+func (rep *Report) calculateProduction() {
+	rep.UnitsInProduction = make([]Production, 0)
+	rep.BuildingsInProduction = make([]Production, 0)
+	rep.Morphing = make([]Production, 0)
+
+	for tag, unit := range rep.Units {
+		ingameUnit := rep.IngameUnits[tag]
+		switch {
+		case ingameUnit.Completed:
+		case len(ingameUnit.MorphTarget) > 0:
+			prod := rep.production(unit.Name, ingameUnit.StartLoop, unit.BuildTime)
+			prod.Count = ingameUnit.MorphCount
+			rep.Morphing = append(rep.Morphing, prod)
+		default:
+			rep.UnitsInProduction = append(
+				rep.UnitsInProduction,
+				rep.production(unit.Name, ingameUnit.StartLoop, unit.BuildTime),
+			)
+		}
+	}
+	for tag, building := range rep.Buildings {
+		if ingameUnit := rep.IngameUnits[tag]; !ingameUnit.Completed {
+			rep.BuildingsInProduction = append(
+				rep.BuildingsInProduction,
+				rep.production(building.Name, ingameUnit.StartLoop, building.BuildTime),
+			)
+		}
+	}
+
+	sortProduction(rep.UnitsInProduction)
+	sortProduction(rep.BuildingsInProduction)
+	sortProduction(rep.Morphing)
+}
+
+// Estimate progress of something which started production at the given loop,
+// and takes `buildTime` seconds to finish.
+func (rep *Report) production(name string, startLoop int64, buildTime int64) Production {
+	prod := Production{Name: name, Count: 1, StartLoop: startLoop}
+
+	ticksPerSecond, err := rep.Replay.TicksPerSecond()
+	if err != nil || buildTime <= 0 {
+		return prod
 	}
+
+	progress := float64(rep.Ticks-startLoop) / (float64(buildTime) * ticksPerSecond)
+	// Build times might be off, eg due to chronoboost, so we'd rather not
+	// claim something is done which is not.
+	prod.Progress = math.Max(0, math.Min(progress, 0.99))
+
+	return prod
+}
+
+func sortProduction(prods []Production) {
+	sort.SliceStable(prods, func(i, j int) bool {
+		if prods[i].Name != prods[j].Name {
+			return prods[i].Name < prods[j].Name
+		}
+		return prods[i].Progress > prods[j].Progress
+	})
 }
 
 func (rep *Report) calculateSupply() {
 	rep.Supply = 0
+	rep.CompletedSupply = 0
 
-	for _, unit := range rep.Units {
+	for tag, unit := range rep.Units {
 		rep.Supply += unit.Supply
+		if rep.IngameUnits[tag].Completed {
+			rep.CompletedSupply += unit.Supply
+		}
+	}
+}
+
+func (rep *Report) calculateValues() {
+	rep.ArmyValue = units.Cost{}
+	rep.TechValue = units.Cost{}
+	rep.EconomyValue = units.Cost{}
+
+	for tag, enrichedUnit := range rep.Units {
+		// Eggs and cocoons are classified by what they are morphing into
+		name := rep.IngameUnits[tag].Name
+		if target := rep.IngameUnits[tag].MorphTarget; len(target) > 0 {
+			name = target
+		}
+
+		if units.Economy[name] {
+			rep.EconomyValue = rep.EconomyValue.Add(enrichedUnit.Cost)
+		} else {
+			rep.ArmyValue = rep.ArmyValue.Add(enrichedUnit.Cost)
+		}
+	}
+
+	for tag, enrichedBuilding := range rep.Buildings {
+		if units.Economy[rep.IngameUnits[tag].Name] {
+			rep.EconomyValue = rep.EconomyValue.Add(enrichedBuilding.Cost)
+		} else {
+			rep.TechValue = rep.TechValue.Add(enrichedBuilding.Cost)
+		}
+	}
+
+	for _, upgrade := range rep.Upgrades {
+		rep.TechValue = rep.TechValue.Add(upgrade.Cost)
 	}
 }
 
-func unitTag(unitTagIndex int64, unitTagRecycle int64) int64 {
-	// Ripped from https://github.com/Blizzard/s2protocol, search `func
-	// unit_tag`. Whoever thought of this system must've been drunk.
-	return (unitTagIndex << 18) + unitTagRecycle
+func (rep *Report) calculateAPM(actions *PlayerActions) {
+	rep.APM = 0
+	rep.EPM = 0
+
+	if actions != nil {
+		rep.APM = actions.APM()
+		rep.EPM = actions.EPM()
+	}
 }
@@ -0,0 +1,238 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"sort"
+)
+
+// Values of the `controlGroupUpdate` field of ControlGroupUpdate events, as
+// per s2protocol.
+const (
+	controlGroupSet         = 0
+	controlGroupAppend      = 1
+	controlGroupRecall      = 2
+	controlGroupClear       = 3
+	controlGroupSetSteal    = 4
+	controlGroupAppendSteal = 5
+)
+
+// ID of the active selection in SelectionDelta events. Lower IDs are the ones
+// of control groups.
+const activeSelection = 10
+
+// Flag of commands issued by right-clicking, rather than by using an ability.
+const cmdFlagSmart = 8
+
+// Command issued by a player, along with the units selected at the time.
+type playerCommand struct {
+	Loop     int64
+	PlayerID int64
+	// Nil for commands without an ability, eg right-clicks
+	Abil  *events.CmdAbil
+	Smart bool
+	// Zero if no unit was targeted
+	TargetTag int64
+	// Nil unless a point was targeted
+	TargetPoint *events.CmdTargetPoint
+	// Tags of the selected units, ascending. Might include units which
+	// died since they were selected.
+	Selected []int64
+}
+
+// Whether the command used an ability without a target, eg to train a unit
+// or research an upgrade.
+func (command *playerCommand) untargeted() bool {
+	return command.Abil != nil && !command.Smart && command.TargetTag == 0 && command.TargetPoint == nil
+}
+
+// Return the commands of all human players in the order they were issued,
+// determined on first use.
+func (replay *Replay) playerCommands() []playerCommand {
+	if replay.commands == nil {
+		replay.commands = findPlayerCommands(replay)
+	}
+
+	return replay.commands
+}
+
+// Commands only tell which ability was used, so the units which used it are
+// taken from the selection, which is tracked from the selection and control
+// group events.
+func findPlayerCommands(replay *Replay) []playerCommand {
+	commands := make([]playerCommand, 0)
+	playerIDs := replay.humanPlayerIDs()
+	selections := make(map[int64]*selection)
+
+	selectionOf := func(userID int64) (*selection, int64, bool) {
+		playerID, ok := playerIDs[userID]
+		if !ok {
+			// Observers
+			return nil, 0, false
+		}
+		if _, ok := selections[playerID]; !ok {
+			selections[playerID] = &selection{groups: make(map[int64][]int64)}
+		}
+
+		return selections[playerID], playerID, true
+	}
+
+	for _, evt := range replay.Rep.GameEvts {
+		switch evt.EvtType.Name {
+		case "SelectionDelta":
+			event := events.SelectionDelta{}
+			if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+				continue
+			}
+			if sel, _, ok := selectionOf(event.UserID.UserID); ok {
+				sel.applyDelta(event)
+			}
+		case "ControlGroupUpdate":
+			event := events.ControlGroupUpdate{}
+			if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+				continue
+			}
+			if sel, _, ok := selectionOf(event.UserID.UserID); ok {
+				sel.applyControlGroupUpdate(event)
+			}
+		case "Cmd":
+			event := events.Cmd{}
+			if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+				continue
+			}
+			sel, playerID, ok := selectionOf(event.UserID.UserID)
+			if !ok {
+				continue
+			}
+
+			command := playerCommand{
+				Loop:        evt.Loop(),
+				PlayerID:    playerID,
+				Abil:        event.Abil,
+				Smart:       event.CmdFlags&cmdFlagSmart != 0,
+				TargetPoint: event.Data.TargetPoint,
+				Selected:    sel.groups[activeSelection],
+			}
+			if event.Data.TargetUnit != nil {
+				command.TargetTag = event.Data.TargetUnit.Tag
+			}
+			commands = append(commands, command)
+		}
+	}
+
+	return commands
+}
+
+// Active selection and control groups of a player. Units are ordered by tag,
+// which is what the indices of deselections refer to. Units which died are
+// not removed, as deselections were found to still count them.
+type selection struct {
+	// Tags by control group ID, including the active selection. Slices are
+	// never modified, only replaced, so they can be shared.
+	groups map[int64][]int64
+}
+
+func (sel *selection) applyDelta(event events.SelectionDelta) {
+	tags := removeMasked(sel.groups[event.ControlGroupID], event.Delta.RemoveMask)
+	sel.groups[event.ControlGroupID] = addTags(tags, event.Delta.AddUnitTags)
+}
+
+func (sel *selection) applyControlGroupUpdate(event events.ControlGroupUpdate) {
+	index := event.ControlGroupIndex
+
+	switch event.ControlGroupUpdate {
+	case controlGroupSet, controlGroupSetSteal:
+		sel.groups[index] = sel.groups[activeSelection]
+	case controlGroupAppend, controlGroupAppendSteal:
+		sel.groups[index] = addTags(sel.groups[index], sel.groups[activeSelection])
+	case controlGroupRecall:
+		sel.groups[activeSelection] = removeMasked(sel.groups[index], event.Mask)
+	case controlGroupClear:
+		delete(sel.groups, index)
+	}
+
+	// Stealing removes the units from all other control groups.
+	if event.ControlGroupUpdate == controlGroupSetSteal || event.ControlGroupUpdate == controlGroupAppendSteal {
+		stolen := make(map[int64]bool)
+		for _, tag := range sel.groups[activeSelection] {
+			stolen[tag] = true
+		}
+
+		for id, tags := range sel.groups {
+			if id == index || id == activeSelection {
+				continue
+			}
+
+			kept := make([]int64, 0, len(tags))
+			for _, tag := range tags {
+				if !stolen[tag] {
+					kept = append(kept, tag)
+				}
+			}
+			sel.groups[id] = kept
+		}
+	}
+}
+
+// Return the tags without the ones removed by the mask.
+func removeMasked(tags []int64, mask events.SelectionMask) []int64 {
+	var removed func(index int) bool
+	switch {
+	case mask.Mask != nil:
+		bits := mask.Mask.Bits()
+		removed = func(index int) bool {
+			return index < len(bits) && bits[index]
+		}
+	case mask.OneIndices != nil:
+		indices := indexSet(mask.OneIndices)
+		removed = func(index int) bool {
+			return indices[index]
+		}
+	case mask.ZeroIndices != nil:
+		indices := indexSet(mask.ZeroIndices)
+		removed = func(index int) bool {
+			return !indices[index]
+		}
+	default:
+		return tags
+	}
+
+	kept := make([]int64, 0, len(tags))
+	for i, tag := range tags {
+		if !removed(i) {
+			kept = append(kept, tag)
+		}
+	}
+
+	return kept
+}
+
+func indexSet(indices []int64) map[int]bool {
+	set := make(map[int]bool, len(indices))
+	for _, index := range indices {
+		set[int(index)] = true
+	}
+
+	return set
+}
+
+// Return the union of both lists of tags, in ascending order.
+func addTags(tags []int64, added []int64) []int64 {
+	if len(added) == 0 {
+		return tags
+	}
+
+	merged := make([]int64, 0, len(tags)+len(added))
+	seen := make(map[int64]bool, len(tags)+len(added))
+	for _, list := range [][]int64{tags, added} {
+		for _, tag := range list {
+			if !seen[tag] {
+				seen[tag] = true
+				merged = append(merged, tag)
+			}
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i] < merged[j] })
+
+	return merged
+}
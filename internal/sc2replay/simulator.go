@@ -0,0 +1,551 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/dragaera/probius/internal/sc2replay/units"
+	"github.com/icza/s2prot"
+)
+
+// Analyses which are fed the events of a simulator, allowing multiple of
+// them to share a single pass over a replay's tracker events.
+type Observer interface {
+	// Called once when the observer is added to a simulator.
+	Start(sim *Simulator) error
+	// Called for every event, *before* it is applied to the simulator's
+	// state. This allows eg looking up the unit which is about to die.
+	Observe(sim *Simulator, evt s2prot.Event) error
+	// Called once all events were processed.
+	Finish(sim *Simulator) error
+}
+
+// Keeps track of the state of the game - units of all players, upgrades,
+// critters and economy - while stepping through a replay's tracker events.
+type Simulator struct {
+	Replay *Replay
+	// Loop of the most recently processed event
+	Loop int64
+
+	// Map containing everything the game considers a unit. This also
+	// includes buildings, mineral patches etc.
+	IngameUnits    map[int64]IngameUnit
+	IngameUpgrades []IngameUpgrade
+
+	CritterStats map[units.Critter]CritterStat
+	// Critters killed by any player, in chronological order
+	CritterKills []CritterKill
+
+	// Units changing hands, in chronological order
+	OwnershipTransfers []OwnershipTransfer
+
+	// Most recent economy sample by player ID
+	Economy map[int64]EconomySample
+
+	// Problems encountered while processing events
+	Diagnostics Diagnostics
+
+	// Index of the next event to process
+	next      int
+	observers []Observer
+}
+
+// Copy of a simulator's state at a given loop. Modifying a snapshot does not
+// affect the simulator, nor does the simulator advancing affect the
+// snapshot.
+type Snapshot struct {
+	Loop int64
+
+	IngameUnits        map[int64]IngameUnit
+	IngameUpgrades     []IngameUpgrade
+	CritterStats       map[units.Critter]CritterStat
+	CritterKills       []CritterKill
+	OwnershipTransfers []OwnershipTransfer
+	Economy            map[int64]EconomySample
+	Diagnostics        []Diagnostic
+}
+
+func NewSimulator(replay *Replay) *Simulator {
+	return &Simulator{
+		Replay:             replay,
+		IngameUnits:        make(map[int64]IngameUnit),
+		IngameUpgrades:     make([]IngameUpgrade, 0),
+		CritterStats:       make(map[units.Critter]CritterStat),
+		CritterKills:       make([]CritterKill, 0),
+		OwnershipTransfers: make([]OwnershipTransfer, 0),
+		Economy:            make(map[int64]EconomySample),
+	}
+}
+
+// Run the given observers over all of the replay's events, in a single pass.
+func Analyse(replay *Replay, observers ...Observer) error {
+	sim := NewSimulator(replay)
+	for _, observer := range observers {
+		if err := sim.AddObserver(observer); err != nil {
+			return err
+		}
+	}
+
+	return sim.Run()
+}
+
+// Add an observer, which will be notified of all events processed from now
+// on.
+func (sim *Simulator) AddObserver(observer Observer) error {
+	if err := observer.Start(sim); err != nil {
+		return err
+	}
+	sim.observers = append(sim.observers, observer)
+
+	return nil
+}
+
+// Whether all events were processed.
+func (sim *Simulator) Done() bool {
+	return sim.next >= len(sim.Replay.Rep.TrackerEvts.Evts)
+}
+
+// Process the next event. Returns false if there are no more events.
+//
+// Errors of observers are returned, whereas events which cannot be processed
+// and inconsistencies in the event stream (eg units dying which never
+// existed) are collected in sim.Diagnostics, as they do not prevent further
+// processing.
+func (sim *Simulator) Step() (bool, error) {
+	if sim.Done() {
+		return false, nil
+	}
+
+	evt := sim.Replay.Rep.TrackerEvts.Evts[sim.next]
+	sim.next += 1
+	sim.Loop = evt.Loop()
+
+	for _, observer := range sim.observers {
+		if err := observer.Observe(sim, evt); err != nil {
+			return true, err
+		}
+	}
+
+	if err := sim.handleEvent(evt); err != nil {
+		sim.Diagnose(SeverityError, evt, err.Error())
+	}
+
+	return true, nil
+}
+
+// Process all events up to and including the given loop.
+func (sim *Simulator) AdvanceTo(loop int64) error {
+	for !sim.Done() && sim.Replay.Rep.TrackerEvts.Evts[sim.next].Loop() <= loop {
+		if _, err := sim.Step(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Process all remaining events, and let the observers know that the replay
+// is finished.
+func (sim *Simulator) Run() error {
+	for !sim.Done() {
+		if _, err := sim.Step(); err != nil {
+			return err
+		}
+	}
+
+	for _, observer := range sim.observers {
+		if err := observer.Finish(sim); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Take a snapshot of the current state.
+func (sim *Simulator) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		Loop:               sim.Loop,
+		IngameUnits:        make(map[int64]IngameUnit, len(sim.IngameUnits)),
+		IngameUpgrades:     append([]IngameUpgrade(nil), sim.IngameUpgrades...),
+		CritterStats:       make(map[units.Critter]CritterStat, len(sim.CritterStats)),
+		CritterKills:       append([]CritterKill(nil), sim.CritterKills...),
+		OwnershipTransfers: append([]OwnershipTransfer(nil), sim.OwnershipTransfers...),
+		Economy:            make(map[int64]EconomySample, len(sim.Economy)),
+		Diagnostics:        append([]Diagnostic(nil), sim.Diagnostics.Entries...),
+	}
+
+	for tag, unit := range sim.IngameUnits {
+		snapshot.IngameUnits[tag] = unit
+	}
+	for critter, stats := range sim.CritterStats {
+		snapshot.CritterStats[critter] = stats
+	}
+	for playerID, sample := range sim.Economy {
+		snapshot.Economy[playerID] = sample
+	}
+
+	return snapshot
+}
+
+// Record a problem caused by the given event. Meant for observers as well.
+func (sim *Simulator) Diagnose(severity Severity, evt s2prot.Event, message string) {
+	sim.Diagnostics.Add(Diagnostic{
+		Severity:  severity,
+		Loop:      evt.Loop(),
+		EventType: evt.EvtType.Name,
+		Message:   message,
+	})
+}
+
+func (sim *Simulator) handleEvent(evt s2prot.Event) error {
+	switch eventType := evt.EvtType.Name; eventType {
+	case "UnitBorn":
+		if err := sim.trackUnitBorn(evt); err != nil {
+			return err
+		}
+	case "UnitInit":
+		if err := sim.trackUnitInit(evt); err != nil {
+			return err
+		}
+	case "UnitDone":
+		// UnitDone is for eg:
+		// - A unit finishing warpin
+		// - A building finishing construction
+		if err := sim.trackUnitDone(evt); err != nil {
+			return err
+		}
+	case "UnitTypeChange":
+		// UnitTypeChange is for eg:
+		// - Buildings transforming (eg gateway => warpgate)
+		// - Creep tumors burrowing
+		// - Larva transforming into eggs
+		// - Hellions transforming into Hellbats
+		if err := sim.trackUnitTypeChange(evt); err != nil {
+			return err
+		}
+	case "UnitDied":
+		if err := sim.trackUnitDied(evt); err != nil {
+			return err
+		}
+	case "UnitOwnerChange":
+		// UnitOwnerChange is for eg:
+		// - Units being neural parasited
+		// - Units being given to allies when a player leaves
+		if err := sim.trackUnitOwnerChange(evt); err != nil {
+			return err
+		}
+	case "Upgrade":
+		if err := sim.trackUpgrade(evt); err != nil {
+			return err
+		}
+	case "PlayerStats":
+		if err := sim.trackPlayerStats(evt); err != nil {
+			return err
+		}
+	default:
+		// fmt.Printf("[%d]: %s by %d\n", evt.Loop(), eventType, evt.UserID())
+	}
+
+	return nil
+}
+
+func (sim *Simulator) trackUnitBorn(evt s2prot.Event) error {
+	event := events.UnitBorn{}
+	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+		return fmt.Errorf("Unable to unmarshal UnitBorn event: %v", err)
+	}
+
+	// Units are only born once they are done, eg after being trained.
+	sim.addUnit(evt, event.UnitTagIndex, event.UnitTagRecycle, event.UnitTypeName, event.UpkeepPlayerID, event.ControlPlayerID, true)
+
+	return nil
+}
+
+func (sim *Simulator) trackUnitInit(evt s2prot.Event) error {
+	event := events.UnitInit{}
+	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+		return fmt.Errorf("Unable to unmarshal UnitInit event: %v", err)
+	}
+
+	// Buildings starting construction, and units starting to warp in.
+	sim.addUnit(evt, event.UnitTagIndex, event.UnitTagRecycle, event.UnitTypeName, event.UpkeepPlayerID, event.ControlPlayerID, false)
+
+	return nil
+}
+
+func (sim *Simulator) trackUnitDone(evt s2prot.Event) error {
+	event := events.UnitDone{}
+	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+		return fmt.Errorf("Unable to unmarshal UnitDone event: %v", err)
+	}
+
+	tag := unitTag(event.UnitTagIndex, event.UnitTagRecycle)
+	unit, ok := sim.IngameUnits[tag]
+	if !ok {
+		sim.Diagnose(
+			SeverityWarning,
+			evt,
+			fmt.Sprintf("Unit with tag %d finished, but does not exist", tag),
+		)
+		return nil
+	}
+	unit.Completed = true
+	sim.IngameUnits[tag] = unit
+
+	return nil
+}
+
+func (sim *Simulator) trackUnitTypeChange(evt s2prot.Event) error {
+	event := events.UnitTypeChange{}
+	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+		return fmt.Errorf("Unable to unmarshal UnitTypeChange event: %v", err)
+	}
+
+	sim.replaceUnit(evt, event.UnitTagIndex, event.UnitTagRecycle, event.UnitTypeName)
+
+	return nil
+}
+
+func (sim *Simulator) trackUnitDied(evt s2prot.Event) error {
+	event := events.UnitDied{}
+	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+		return fmt.Errorf("Unable to unmarshal UnitDied event: %v", err)
+	}
+
+	if err := sim.trackCritterKill(evt.Loop(), event); err != nil {
+		return err
+	}
+
+	sim.removeUnit(evt, event.UnitTagIndex, event.UnitTagRecycle)
+
+	return nil
+}
+
+func (sim *Simulator) trackUnitOwnerChange(evt s2prot.Event) error {
+	event := events.UnitOwnerChange{}
+	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+		return fmt.Errorf("Unable to unmarshal UnitOwnerChange event: %v", err)
+	}
+
+	tag := unitTag(event.UnitTagIndex, event.UnitTagRecycle)
+	unit, ok := sim.IngameUnits[tag]
+	if !ok {
+		sim.Diagnose(
+			SeverityWarning,
+			evt,
+			fmt.Sprintf("Unit with tag %d changed owner, but does not exist", tag),
+		)
+		return nil
+	}
+
+	sim.OwnershipTransfers = append(sim.OwnershipTransfers, OwnershipTransfer{
+		Loop:             evt.Loop(),
+		Unit:             unit.Name,
+		FromOwnerID:      unit.OwnerID,
+		ToOwnerID:        event.UpkeepPlayerID,
+		FromControllerID: unit.ControllerID,
+		ToControllerID:   event.ControlPlayerID,
+	})
+
+	unit.OwnerID = event.UpkeepPlayerID
+	unit.ControllerID = event.ControlPlayerID
+	sim.IngameUnits[tag] = unit
+
+	return nil
+}
+
+// Record who killed a critter, if the unit which died is one.
+func (sim *Simulator) trackCritterKill(loop int64, event events.UnitDied) error {
+	unit, ok := sim.IngameUnits[unitTag(event.UnitTagIndex, event.UnitTagRecycle)]
+	if !ok {
+		// Will be reported by removeUnit
+		return nil
+	}
+
+	critter, ok := units.Critters[unit.Name]
+	if !ok {
+		return nil
+	}
+
+	kill := CritterKill{
+		Loop:    loop,
+		Tag:     unitTag(event.UnitTagIndex, event.UnitTagRecycle),
+		Critter: critter,
+	}
+	if event.KillerPlayerID != nil {
+		kill.KillerPlayerID = *event.KillerPlayerID
+	}
+	if event.KillerUnitTagIndex != nil && event.KillerUnitTagRecycle != nil {
+		tag := unitTag(*event.KillerUnitTagIndex, *event.KillerUnitTagRecycle)
+		if killer, ok := sim.IngameUnits[tag]; ok {
+			kill.KillerUnit = killer.Name
+			if enrichedUnit, ok := sim.Replay.Catalog().Units[killer.Name]; ok {
+				kill.KillerUnit = enrichedUnit.Name
+			} else if enrichedBuilding, ok := sim.Replay.Catalog().Buildings[killer.Name]; ok {
+				kill.KillerUnit = enrichedBuilding.Name
+			}
+		}
+	}
+
+	sim.CritterKills = append(sim.CritterKills, kill)
+
+	return nil
+}
+
+func (sim *Simulator) addUnit(evt s2prot.Event, index int64, recycle int64, name string, ownerID int64, controllerID int64, completed bool) {
+	tag := unitTag(index, recycle)
+
+	if existing, ok := sim.IngameUnits[tag]; ok {
+		// Unit with given tag exists already => That's a mistake
+		sim.Diagnose(
+			SeverityWarning,
+			evt,
+			fmt.Sprintf("Unit tag %d reused by %s, keeping %s", tag, name, existing.Name),
+		)
+		return
+	}
+	sim.IngameUnits[tag] = IngameUnit{
+		Index:        index,
+		Recycle:      recycle,
+		Name:         name,
+		OwnerID:      ownerID,
+		ControllerID: controllerID,
+		Completed:    completed,
+		StartLoop:    evt.Loop(),
+	}
+
+	// Special treatment for critters :)
+	if critter, ok := units.Critters[name]; ok {
+		oldStats := sim.CritterStats[critter]
+		newStats := CritterStat{Total: oldStats.Total + 1, Alive: oldStats.Alive + 1}
+		sim.CritterStats[critter] = newStats
+	}
+}
+
+// Units changing type either morph (eg larvae into eggs, hydralisks into
+// lurker cocoons), finish morphing (eg eggs into drones), or transform
+// without taking any time (eg hellions into hellbats).
+func (sim *Simulator) replaceUnit(evt s2prot.Event, index int64, recycle int64, name string) {
+	tag := unitTag(index, recycle)
+
+	if _, ok := sim.IngameUnits[tag]; !ok {
+		// Trying to replace a nonexistant unit
+		sim.Diagnose(
+			SeverityWarning,
+			evt,
+			fmt.Sprintf("Unit with tag %d turned into %s, but does not exist", tag, name),
+		)
+		return
+	}
+	// Cannot change struct fields in maps
+	existing := sim.IngameUnits[tag]
+	wasMorphing := sim.isMorphing(existing.Name)
+	existing.Name = name
+	existing.MorphTarget = ""
+	existing.MorphCount = 0
+
+	if target, ok := sim.Replay.Catalog().Morphs[name]; ok {
+		existing.MorphTarget = target
+		existing.MorphCount = 1
+	} else if name == units.Egg {
+		if target, ok := sim.Replay.eggHatches()[eggKey{Tag: tag, StartLoop: evt.Loop()}]; ok {
+			existing.MorphTarget = target
+			existing.MorphCount = units.UnitsPerEgg(target)
+		}
+	}
+
+	switch {
+	case sim.isMorphing(name):
+		existing.Completed = false
+		existing.StartLoop = evt.Loop()
+	case wasMorphing:
+		existing.Completed = true
+		existing.StartLoop = evt.Loop()
+	}
+
+	sim.IngameUnits[tag] = existing
+}
+
+func (sim *Simulator) trackUpgrade(evt s2prot.Event) error {
+	event := events.Upgrade{}
+	if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+		return fmt.Errorf("Unable to unmarshal Upgrade event: %v", err)
+	}
+
+	sim.IngameUpgrades = append(sim.IngameUpgrades, IngameUpgrade{Name: event.UpgradeTypeName, OwnerID: event.PlayerID})
+
+	return nil
+}
+
+func (sim *Simulator) trackPlayerStats(evt s2prot.Event) error {
+	event, err := parsePlayerStats(evt)
+	if err != nil {
+		return err
+	}
+
+	sim.Economy[event.PlayerID] = sampleFromStats(evt.Loop(), event.Stats)
+
+	return nil
+}
+
+func (sim *Simulator) removeUnit(evt s2prot.Event, index int64, recycle int64) {
+	tag := unitTag(index, recycle)
+
+	unit, ok := sim.IngameUnits[tag]
+	if !ok {
+		// Trying to remove a nonexistant unit
+		sim.Diagnose(
+			SeverityWarning,
+			evt,
+			fmt.Sprintf("Unit with tag %d died, but does not exist", tag),
+		)
+		return
+	}
+
+	// Special treatment for critters :)
+	if critter, ok := units.Critters[unit.Name]; ok {
+		oldStats := sim.CritterStats[critter]
+		newStats := CritterStat{Total: oldStats.Total, Alive: oldStats.Alive - 1}
+		sim.CritterStats[critter] = newStats
+	}
+
+	delete(sim.IngameUnits, tag)
+}
+
+// Whether units of the given ingame name are eggs or cocoons.
+func (sim *Simulator) isMorphing(name string) bool {
+	if name == units.Egg {
+		return true
+	}
+	_, ok := sim.Replay.Catalog().Morphs[name]
+
+	return ok
+}
+
+// Supply of all ingame units owned by the given player, as of the events
+// processed so far.
+func (sim *Simulator) supplyOf(playerID int64) float64 {
+	supply := 0.0
+	catalog := sim.Replay.Catalog()
+
+	for _, unit := range sim.IngameUnits {
+		if unit.OwnerID != playerID {
+			continue
+		}
+
+		if enrichedUnit, ok := morphingUnit(catalog, unit); ok {
+			supply += enrichedUnit.Supply
+		} else if enrichedUnit, ok := catalog.Units[unit.Name]; ok {
+			supply += enrichedUnit.Supply
+		}
+	}
+
+	return supply
+}
+
+func unitTag(unitTagIndex int64, unitTagRecycle int64) int64 {
+	// Ripped from https://github.com/Blizzard/s2protocol, search `func
+	// unit_tag`. Whoever thought of this system must've been drunk.
+	return (unitTagIndex << 18) + unitTagRecycle
+}
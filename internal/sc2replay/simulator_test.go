@@ -0,0 +1,185 @@
+package sc2replay
+
+import (
+	"github.com/icza/s2prot"
+	"github.com/icza/s2prot/rep"
+	"math"
+	"testing"
+)
+
+// Build a replay of the current patch consisting of the given tracker events
+// only.
+func testReplay(evts ...s2prot.Event) *Replay {
+	return &Replay{
+		Rep: &rep.Rep{
+			Header: rep.Header{Struct: s2prot.Struct{
+				"version":          s2prot.Struct{"baseBuild": int64(math.MaxInt64)},
+				"elapsedGameLoops": evts[len(evts)-1].Loop(),
+			}},
+			TrackerEvts: &rep.TrackerEvts{Evts: evts},
+		},
+	}
+}
+
+func trackerEvent(loop int64, name string, fields s2prot.Struct) s2prot.Event {
+	fields["loop"] = loop
+	return s2prot.Event{Struct: fields, EvtType: &s2prot.EvtType{Name: name}}
+}
+
+func unitBorn(loop int64, index int64, name string, playerID int64) s2prot.Event {
+	return trackerEvent(loop, "UnitBorn", s2prot.Struct{
+		"unitTagIndex":    index,
+		"unitTagRecycle":  int64(1),
+		"unitTypeName":    name,
+		"controlPlayerId": playerID,
+		"upkeepPlayerId":  playerID,
+	})
+}
+
+func unitInit(loop int64, index int64, name string, playerID int64) s2prot.Event {
+	evt := unitBorn(loop, index, name, playerID)
+	evt.EvtType = &s2prot.EvtType{Name: "UnitInit"}
+	return evt
+}
+
+func unitDone(loop int64, index int64) s2prot.Event {
+	return trackerEvent(loop, "UnitDone", s2prot.Struct{
+		"unitTagIndex":   index,
+		"unitTagRecycle": int64(1),
+	})
+}
+
+func unitTypeChange(loop int64, index int64, name string) s2prot.Event {
+	return trackerEvent(loop, "UnitTypeChange", s2prot.Struct{
+		"unitTagIndex":   index,
+		"unitTagRecycle": int64(1),
+		"unitTypeName":   name,
+	})
+}
+
+func unitDied(loop int64, index int64) s2prot.Event {
+	return trackerEvent(loop, "UnitDied", s2prot.Struct{
+		"unitTagIndex":   index,
+		"unitTagRecycle": int64(1),
+	})
+}
+
+// A zerg player and a terran player, each starting with a townhall and two
+// workers.
+func testGame() *Replay {
+	return testReplay(
+		unitBorn(0, 1, "Hatchery", 1),
+		unitBorn(0, 2, "Drone", 1),
+		unitBorn(0, 3, "Drone", 1),
+		unitBorn(0, 4, "CommandCenter", 2),
+		unitBorn(0, 5, "SCV", 2),
+		unitBorn(0, 6, "SCV", 2),
+		unitBorn(100, 7, "Zergling", 1),
+		unitBorn(100, 8, "Zergling", 1),
+		unitInit(200, 9, "Barracks", 2),
+		unitBorn(300, 10, "Roach", 1),
+		unitTypeChange(400, 10, "RavagerCocoon"),
+		unitDied(500, 2),
+		unitDone(600, 9),
+		unitTypeChange(700, 10, "Ravager"),
+		unitBorn(800, 11, "Marine", 2),
+	)
+}
+
+func TestSimulatorSnapshot(t *testing.T) {
+	tests := []struct {
+		loop          int64
+		wantUnits     int
+		wantSupply    map[int64]float64
+		wantCompleted map[int64]bool
+		wantNames     map[int64]string
+	}{
+		{
+			loop:       0,
+			wantUnits:  6,
+			wantSupply: map[int64]float64{1: 2, 2: 2},
+		},
+		{
+			loop:       100,
+			wantUnits:  8,
+			wantSupply: map[int64]float64{1: 3, 2: 2},
+		},
+		{
+			loop:          250,
+			wantUnits:     9,
+			wantSupply:    map[int64]float64{1: 3, 2: 2},
+			wantCompleted: map[int64]bool{9: false},
+		},
+		{
+			// The cocoon counts as the ravager it turns into.
+			loop:          450,
+			wantUnits:     10,
+			wantSupply:    map[int64]float64{1: 6, 2: 2},
+			wantCompleted: map[int64]bool{10: false},
+			wantNames:     map[int64]string{10: "RavagerCocoon"},
+		},
+		{
+			loop:          650,
+			wantUnits:     9,
+			wantSupply:    map[int64]float64{1: 5, 2: 2},
+			wantCompleted: map[int64]bool{9: true},
+		},
+		{
+			loop:          1000,
+			wantUnits:     10,
+			wantSupply:    map[int64]float64{1: 5, 2: 3},
+			wantCompleted: map[int64]bool{10: true},
+			wantNames:     map[int64]string{10: "Ravager"},
+		},
+	}
+
+	sim := NewSimulator(testGame())
+	for _, test := range tests {
+		if err := sim.AdvanceTo(test.loop); err != nil {
+			t.Fatalf("Unable to advance to loop %d: %v", test.loop, err)
+		}
+		snapshot := sim.Snapshot()
+
+		if len(snapshot.Diagnostics) > 0 {
+			t.Errorf("Loop %d: unexpected diagnostics: %v", test.loop, snapshot.Diagnostics)
+		}
+		if len(snapshot.IngameUnits) != test.wantUnits {
+			t.Errorf("Loop %d: %d units, want %d", test.loop, len(snapshot.IngameUnits), test.wantUnits)
+		}
+		for playerID, want := range test.wantSupply {
+			if got := sim.supplyOf(playerID); got != want {
+				t.Errorf("Loop %d: supply of player %d = %v, want %v", test.loop, playerID, got, want)
+			}
+		}
+		for index, want := range test.wantCompleted {
+			if got := snapshot.IngameUnits[unitTag(index, 1)].Completed; got != want {
+				t.Errorf("Loop %d: unit %d completed = %v, want %v", test.loop, index, got, want)
+			}
+		}
+		for index, want := range test.wantNames {
+			if got := snapshot.IngameUnits[unitTag(index, 1)].Name; got != want {
+				t.Errorf("Loop %d: unit %d = %v, want %v", test.loop, index, got, want)
+			}
+		}
+	}
+}
+
+func TestSimulatorSnapshotIsCopy(t *testing.T) {
+	sim := NewSimulator(testGame())
+	if err := sim.AdvanceTo(0); err != nil {
+		t.Fatalf("Unable to advance to loop 0: %v", err)
+	}
+	snapshot := sim.Snapshot()
+
+	if err := sim.AdvanceTo(1000); err != nil {
+		t.Fatalf("Unable to advance to loop 1000: %v", err)
+	}
+	if snapshot.Loop != 0 || len(snapshot.IngameUnits) != 6 {
+		t.Errorf("Snapshot changed with the simulator: loop %d, %d units", snapshot.Loop, len(snapshot.IngameUnits))
+	}
+
+	delete(snapshot.IngameUnits, unitTag(1, 1))
+	if _, ok := sim.IngameUnits[unitTag(1, 1)]; !ok {
+		t.Errorf("Modifying the snapshot changed the simulator")
+	}
+}
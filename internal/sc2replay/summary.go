@@ -0,0 +1,110 @@
+package sc2replay
+
+import (
+	"fmt"
+	"github.com/icza/s2prot/rep"
+	"time"
+)
+
+type PlayerSummary struct {
+	PlayerID int64
+	Name     string
+	Race     string
+	TeamID   int64
+	// Zero if unknown, eg in unranked games.
+	MMR    int64
+	Winner bool
+}
+
+type Summary struct {
+	Map      string
+	Matchup  string
+	Time     time.Time
+	Duration time.Duration
+	Region   string
+	Version  string
+	Players  []PlayerSummary
+}
+
+// Return the names of the winning players, if any.
+func (summary *Summary) Winners() []string {
+	winners := make([]string, 0)
+	for _, player := range summary.Players {
+		if player.Winner {
+			winners = append(winners, player.Name)
+		}
+	}
+
+	return winners
+}
+
+// Return a summary of the game the replay is of, based solely on the
+// replay's header, details, init data and metadata.
+func (replay *Replay) Summary() (Summary, error) {
+	details := replay.Rep.Details
+
+	duration, err := replay.DurationAt(replay.Rep.Header.Loops())
+	if err != nil {
+		return Summary{}, fmt.Errorf("Unable to determine game length: %v", err)
+	}
+
+	summary := Summary{
+		Map:      details.Title(),
+		Matchup:  details.Matchup(),
+		Time:     details.Time(),
+		Duration: duration,
+		Region:   replay.Rep.InitData.GameDescription.Region().Name,
+		Version:  replay.Rep.Header.VersionString(),
+		Players:  make([]PlayerSummary, 0, len(details.Players())),
+	}
+
+	// Observers are not part of the details' player list, and the
+	// list's order matches the player IDs.
+	for i, player := range details.Players() {
+		playerID := int64(i + 1)
+
+		name, err := replay.PlayerName(playerID)
+		if err != nil {
+			// AI players have no user, but do have a name here.
+			name = player.Name
+		}
+
+		summary.Players = append(summary.Players, PlayerSummary{
+			PlayerID: playerID,
+			Name:     name,
+			Race:     player.Race().Name,
+			TeamID:   player.TeamID(),
+			MMR:      replay.PlayerMMR(playerID),
+			Winner:   player.Result() == rep.ResultVictory,
+		})
+	}
+
+	return summary, nil
+}
+
+// Return the MMR of the player with the given player ID at the start of the
+// game, or zero if it is unknown.
+func (replay *Replay) PlayerMMR(playerID int64) int64 {
+	if player, ok := replay.Rep.TrackerEvts.PIDPlayerDescMap[playerID]; ok {
+		if player.UserID >= 0 && int(player.UserID) < len(replay.Rep.InitData.UserInitDatas) {
+			user := replay.Rep.InitData.UserInitDatas[player.UserID]
+			if rating := user.Int("scaledRating"); rating > 0 {
+				return rating
+			}
+		}
+	}
+
+	// Older replays only contain it in the metadata, if at all.
+	if replay.Rep.Metadata.Struct == nil {
+		return 0
+	}
+	for _, player := range replay.Rep.Metadata.Players() {
+		// Metadata is JSON-encoded, so all its numbers are floats, which
+		// `PlayerID()` does not expect.
+		if int64(player.Float("PlayerID")) == playerID && player.MMR() > 0 {
+			return int64(player.MMR())
+		}
+	}
+
+	return 0
+}
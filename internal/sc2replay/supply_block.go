@@ -0,0 +1,183 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/dragaera/probius/internal/sc2replay/units"
+	"github.com/icza/s2prot"
+)
+
+// An interval during which a player was unable to produce units due to
+// lacking supply.
+type SupplyBlock struct {
+	Start int64
+	End   int64
+}
+
+// Length of the block in ticks.
+func (block *SupplyBlock) Duration() int64 {
+	return block.End - block.Start
+}
+
+// Detects supply blocks.
+//
+// The game samples used and provided supply of every player roughly every
+// seven seconds. A player is considered blocked from the first sample at
+// which their used supply reached the provided supply. Their block ends when
+// a supply-providing unit or building finished, if that lifted the block, or
+// at the first sample where they are no longer blocked otherwise.
+type SupplyBlocks struct {
+	Replay *Replay
+
+	// Blocks by player ID, in chronological order.
+	Blocks map[int64][]SupplyBlock
+
+	// Start of currently ongoing block, by player ID
+	blockedSince map[int64]int64
+	// First completion of a supply provider during an ongoing block, by
+	// player ID
+	providedAt map[int64]int64
+}
+
+// Call this to detect the supply blocks.
+func (sb *SupplyBlocks) Generate() error {
+	return Analyse(sb.Replay, sb)
+}
+
+func (sb *SupplyBlocks) Start(sim *Simulator) error {
+	sb.Blocks = make(map[int64][]SupplyBlock)
+	sb.blockedSince = make(map[int64]int64)
+	sb.providedAt = make(map[int64]int64)
+
+	return nil
+}
+
+func (sb *SupplyBlocks) Observe(sim *Simulator, evt s2prot.Event) error {
+	if evt.EvtType.Name == "PlayerStats" {
+		event, err := parsePlayerStats(evt)
+		if err != nil {
+			return err
+		}
+
+		sample := sampleFromStats(evt.Loop(), event.Stats)
+		blocked := sample.Supply >= sample.SupplyCap && sample.SupplyCap < units.SupplyLimit
+		start, ongoing := sb.blockedSince[event.PlayerID]
+
+		switch {
+		case blocked && !ongoing:
+			sb.blockedSince[event.PlayerID] = evt.Loop()
+		case blocked && ongoing:
+			// Any supply provided in the meantime did not lift the
+			// block.
+			delete(sb.providedAt, event.PlayerID)
+		case !blocked && ongoing:
+			end := evt.Loop()
+			if loop, ok := sb.providedAt[event.PlayerID]; ok {
+				end = loop
+			}
+			sb.Blocks[event.PlayerID] = append(sb.Blocks[event.PlayerID], SupplyBlock{Start: start, End: end})
+			delete(sb.blockedSince, event.PlayerID)
+			delete(sb.providedAt, event.PlayerID)
+		}
+
+		return nil
+	}
+
+	playerID, provided, err := sb.supplyProvided(evt, sim)
+	if err != nil {
+		sim.Diagnose(SeverityError, evt, err.Error())
+	}
+	if provided {
+		if _, ongoing := sb.blockedSince[playerID]; ongoing {
+			if _, ok := sb.providedAt[playerID]; !ok {
+				sb.providedAt[playerID] = evt.Loop()
+			}
+		}
+	}
+
+	return nil
+}
+
+func (sb *SupplyBlocks) Finish(sim *Simulator) error {
+	// Blocks lasting until the end of the game
+	for playerID, start := range sb.blockedSince {
+		end := sb.Replay.Rep.Header.Loops()
+		sb.Blocks[playerID] = append(sb.Blocks[playerID], SupplyBlock{Start: start, End: end})
+	}
+
+	return nil
+}
+
+// Return the blocks of the given player which started up until the given
+// amount of ticks. Blocks ongoing at that point are cut short.
+func (sb *SupplyBlocks) Until(playerID int64, ticks int64) []SupplyBlock {
+	blocks := make([]SupplyBlock, 0)
+
+	for _, block := range sb.Blocks[playerID] {
+		if block.Start > ticks {
+			break
+		}
+		if block.End > ticks {
+			block.End = ticks
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks
+}
+
+// Return the total duration in ticks the given player was supply blocked.
+func (sb *SupplyBlocks) Total(playerID int64) int64 {
+	total := int64(0)
+	for _, block := range sb.Blocks[playerID] {
+		total += block.Duration()
+	}
+
+	return total
+}
+
+// Check whether the event marks the completion of a supply-providing unit or
+// building. The passed simulator must reflect the state *before* the event was
+// handled.
+func (sb *SupplyBlocks) supplyProvided(evt s2prot.Event, state *Simulator) (int64, bool, error) {
+	switch eventType := evt.EvtType.Name; eventType {
+	case "UnitDone":
+		event := events.UnitDone{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return 0, false, fmt.Errorf("Unable to unmarshal UnitDone event: %v", err)
+		}
+
+		unit, ok := state.IngameUnits[unitTag(event.UnitTagIndex, event.UnitTagRecycle)]
+		if !ok {
+			return 0, false, nil
+		}
+		_, provides := units.SupplyProviders[unit.Name]
+		return unit.OwnerID, provides, nil
+	case "UnitTypeChange":
+		// Eg overlords hatching from eggs. Morphs between supply
+		// providers (overlord => overseer) don't add any supply.
+		event := events.UnitTypeChange{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return 0, false, fmt.Errorf("Unable to unmarshal UnitTypeChange event: %v", err)
+		}
+
+		unit, ok := state.IngameUnits[unitTag(event.UnitTagIndex, event.UnitTagRecycle)]
+		if !ok {
+			return 0, false, nil
+		}
+		_, providedBefore := units.SupplyProviders[unit.Name]
+		_, providesNow := units.SupplyProviders[event.UnitTypeName]
+		return unit.OwnerID, providesNow && !providedBefore, nil
+	case "UnitBorn":
+		event := events.UnitBorn{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return 0, false, fmt.Errorf("Unable to unmarshal UnitBorn event: %v", err)
+		}
+
+		_, provides := units.SupplyProviders[event.UnitTypeName]
+		return event.UpkeepPlayerID, provides, nil
+	}
+
+	return 0, false, nil
+}
@@ -0,0 +1,29 @@
+package units
+
+// Ingame names of the abilities used by race mechanics.
+const (
+	SpawnLarva  = "SpawnLarva"
+	ChronoBoost = "ChronoBoost"
+	// Chronoboost as of patch 4.0, which made it no longer depend on the
+	// energy of the nexus casting it.
+	ChronoBoostEnergyCost = "ChronoBoostEnergyCost"
+	CalldownMULE          = "CalldownMULE"
+	ScannerSweep          = "ScannerSweep"
+)
+
+// Identifies an ability in the commands of game events. Links differ between
+// game versions, so they are only part of catalogs of the base builds they
+// were verified for. A link of zero means it is not known, in which case
+// analyses recognize the ability by how it is used.
+type Ability struct {
+	Link     int64 `json:"link"`
+	CmdIndex int64 `json:"cmdIndex"`
+	// Duration of its effect, in seconds of game time. Only set where
+	// analyses make use of it.
+	Duration int64 `json:"duration"`
+}
+
+// Whether commands using this ability can be recognized.
+func (ability Ability) Known() bool {
+	return ability.Link > 0
+}
@@ -0,0 +1,309 @@
+package units
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Catalog files shipped with Probius. `default.json` contains the values of
+// the current patch, other files only what differs in older patches. Of the
+// balance changes of past patches, only the Mothership Core (`pre-4.0.json`)
+// is covered so far, everything else uses the current values.
+//
+//go:embed data/*.json
+var embeddedCatalogs embed.FS
+
+const defaultCatalogPath string = "data/default.json"
+
+// Static information about units, buildings and upgrades, by their ingame
+// name.
+type Catalog struct {
+	Units     map[string]Unit     `json:"units"`
+	Buildings map[string]Building `json:"buildings"`
+	Upgrades  map[string]Upgrade  `json:"upgrades"`
+	// Ingame names of cocoons, mapped to the ingame name of the unit they
+	// are morphing into.
+	Morphs map[string]string `json:"morphs"`
+	// Abilities by their ingame name
+	Abilities map[string]Ability `json:"abilities"`
+	// Patterns, as understood by `path.Match`, of names which are
+	// deliberately not part of the catalog, such as beacons or cosmetic
+	// upgrades.
+	Ignored []string `json:"ignored"`
+}
+
+// Entries of a catalog file, which apply to replays of the given range of
+// base builds.
+type CatalogFile struct {
+	// Inclusive. Zero if unbounded.
+	MinBuild int64 `json:"minBuild"`
+	MaxBuild int64 `json:"maxBuild"`
+
+	Catalog
+}
+
+// Maximum build, or the highest possible build if unbounded.
+func (file *CatalogFile) upperBound() int64 {
+	if file.MaxBuild > 0 {
+		return file.MaxBuild
+	}
+
+	return math.MaxInt64
+}
+
+func (file *CatalogFile) appliesTo(baseBuild int64) bool {
+	if file.MinBuild > 0 && baseBuild < file.MinBuild {
+		return false
+	}
+	if baseBuild > file.upperBound() {
+		return false
+	}
+
+	return true
+}
+
+var catalogs = struct {
+	sync.Mutex
+
+	// Files in the order they are applied, later ones overriding earlier
+	// ones.
+	files []CatalogFile
+	// Merged catalogs by base build
+	byBuild map[int64]*Catalog
+	// Hex-encoded SHA-256 of the override files loaded so far, empty if
+	// none were.
+	overridesHash string
+}{}
+
+func init() {
+	files, err := loadEmbeddedCatalogs()
+	if err != nil {
+		// Embedded files are part of the binary, so this can only be
+		// a programming mistake.
+		panic(err)
+	}
+
+	catalogs.files = files
+	catalogs.byBuild = make(map[int64]*Catalog)
+}
+
+// Return the catalog applying to replays of the given base build.
+func CatalogFor(baseBuild int64) *Catalog {
+	catalogs.Lock()
+	defer catalogs.Unlock()
+
+	if catalog, ok := catalogs.byBuild[baseBuild]; ok {
+		return catalog
+	}
+
+	catalog := newCatalog()
+	for i := range catalogs.files {
+		if catalogs.files[i].appliesTo(baseBuild) {
+			catalog.merge(&catalogs.files[i].Catalog)
+		}
+	}
+	catalogs.byBuild[baseBuild] = catalog
+
+	return catalog
+}
+
+// Return the catalog of the current patch.
+func DefaultCatalog() *Catalog {
+	// Base builds increase with every patch
+	return CatalogFor(math.MaxInt64)
+}
+
+// Load a file containing a list of catalog files, which override or extend
+// the built-in ones. This is meant to be called on startup, as reports
+// generated earlier will not reflect the changes.
+func LoadCatalogOverrides(filePath string) error {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("Unable to read catalog overrides: %v", err)
+	}
+
+	files := make([]CatalogFile, 0)
+	if err := json.Unmarshal(data, &files); err != nil {
+		return fmt.Errorf("Unable to parse catalog overrides: %v", err)
+	}
+
+	AddCatalogFiles(files...)
+
+	catalogs.Lock()
+	defer catalogs.Unlock()
+	hasher := sha256.New()
+	hasher.Write([]byte(catalogs.overridesHash))
+	hasher.Write(data)
+	catalogs.overridesHash = hex.EncodeToString(hasher.Sum(nil))
+
+	return nil
+}
+
+// Return a hash of the contents of all override files loaded via
+// `LoadCatalogOverrides`, or an empty string if none were. As overrides
+// change the results of analyses, this allows telling apart results
+// generated with different ones.
+func CatalogOverridesHash() string {
+	catalogs.Lock()
+	defer catalogs.Unlock()
+
+	return catalogs.overridesHash
+}
+
+// Add catalog files which override or extend the built-in ones.
+func AddCatalogFiles(files ...CatalogFile) {
+	catalogs.Lock()
+	defer catalogs.Unlock()
+
+	catalogs.files = append(catalogs.files, files...)
+	catalogs.byBuild = make(map[int64]*Catalog)
+}
+
+// Whether the given unit or upgrade name is deliberately not part of the
+// catalog.
+func (catalog *Catalog) Ignores(name string) bool {
+	for _, pattern := range catalog.Ignored {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Return the human-readable name of the unit, building or upgrade with the
+// given ingame or human-readable name. Case and spaces are ignored, so eg
+// `warpgate` matches the Warp Gate.
+func (catalog *Catalog) LookupName(name string) (string, bool) {
+	normalize := func(name string) string {
+		return strings.ToLower(strings.ReplaceAll(name, " ", ""))
+	}
+	wanted := normalize(name)
+
+	// Human-readable names by ingame name
+	names := make(map[string]string)
+	for ingameName, unit := range catalog.Units {
+		names[ingameName] = unit.Name
+	}
+	for ingameName, building := range catalog.Buildings {
+		names[ingameName] = building.Name
+	}
+	for ingameName, upgrade := range catalog.Upgrades {
+		names[ingameName] = upgrade.Name
+	}
+
+	// Human-readable names take precedence, as some of them match the
+	// ingame name of something else.
+	for _, humanName := range names {
+		if normalize(humanName) == wanted {
+			return humanName, true
+		}
+	}
+	for ingameName, humanName := range names {
+		if normalize(ingameName) == wanted {
+			return humanName, true
+		}
+	}
+
+	return "", false
+}
+
+// Return the ingame name of the ability used by a command, if it is known.
+func (catalog *Catalog) AbilityName(link int64, cmdIndex int64) (string, bool) {
+	for name, ability := range catalog.Abilities {
+		if ability.Known() && ability.Link == link && ability.CmdIndex == cmdIndex {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+func newCatalog() *Catalog {
+	return &Catalog{
+		Units:     make(map[string]Unit),
+		Buildings: make(map[string]Building),
+		Upgrades:  make(map[string]Upgrade),
+		Morphs:    make(map[string]string),
+		Abilities: make(map[string]Ability),
+		Ignored:   make([]string, 0),
+	}
+}
+
+func (catalog *Catalog) merge(other *Catalog) {
+	for name, unit := range other.Units {
+		catalog.Units[name] = unit
+	}
+	for name, building := range other.Buildings {
+		catalog.Buildings[name] = building
+	}
+	for name, upgrade := range other.Upgrades {
+		catalog.Upgrades[name] = upgrade
+	}
+	for name, target := range other.Morphs {
+		catalog.Morphs[name] = target
+	}
+	for name, ability := range other.Abilities {
+		catalog.Abilities[name] = ability
+	}
+	catalog.Ignored = append(catalog.Ignored, other.Ignored...)
+}
+
+// Load the embedded catalog files, with the default one first, and the
+// others by descending maximum build. Files for older patches thus override
+// those of newer ones.
+func loadEmbeddedCatalogs() ([]CatalogFile, error) {
+	defaultFile, err := loadEmbeddedCatalog(defaultCatalogPath)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := fs.Glob(embeddedCatalogs, "data/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	older := make([]CatalogFile, 0, len(paths))
+	for _, p := range paths {
+		if p == defaultCatalogPath {
+			continue
+		}
+
+		file, err := loadEmbeddedCatalog(p)
+		if err != nil {
+			return nil, err
+		}
+		older = append(older, file)
+	}
+
+	sort.SliceStable(older, func(i, j int) bool {
+		return older[i].upperBound() > older[j].upperBound()
+	})
+
+	return append([]CatalogFile{defaultFile}, older...), nil
+}
+
+func loadEmbeddedCatalog(p string) (CatalogFile, error) {
+	file := CatalogFile{}
+
+	data, err := embeddedCatalogs.ReadFile(p)
+	if err != nil {
+		return file, fmt.Errorf("Unable to read catalog %v: %v", p, err)
+	}
+
+	if err := json.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("Unable to parse catalog %v: %v", p, err)
+	}
+
+	return file, nil
+}
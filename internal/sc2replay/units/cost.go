@@ -0,0 +1,64 @@
+package units
+
+import (
+	"fmt"
+)
+
+type Cost struct {
+	Minerals int64 `json:"minerals"`
+	Vespene  int64 `json:"vespene"`
+}
+
+func (cost Cost) Add(other Cost) Cost {
+	return Cost{
+		Minerals: cost.Minerals + other.Minerals,
+		Vespene:  cost.Vespene + other.Vespene,
+	}
+}
+
+func (cost Cost) Total() int64 {
+	return cost.Minerals + cost.Vespene
+}
+
+// Format as eg `4,350/1,200`.
+func (cost Cost) String() string {
+	return fmt.Sprintf("%v/%v", formatThousands(cost.Minerals), formatThousands(cost.Vespene))
+}
+
+// Ingame names of units and buildings which are an investment into a
+// player's economy - workers, townhalls, gas and supply - rather than into
+// their army or tech.
+var Economy = map[string]bool{
+	// Protoss
+	"Probe":       true,
+	"Nexus":       true,
+	"Pylon":       true,
+	"Assimilator": true,
+	// Terran
+	"SCV":                  true,
+	"CommandCenter":        true,
+	"CommandCenterFlying":  true,
+	"OrbitalCommand":       true,
+	"OrbitalCommandFlying": true,
+	"PlanetaryFortress":    true,
+	"SupplyDepot":          true,
+	"SupplyDepotLowered":   true,
+	"Refinery":             true,
+	// Zerg
+	"Drone":             true,
+	"Hatchery":          true,
+	"Extractor":         true,
+	"Overlord":          true,
+	"OverlordTransport": true,
+}
+
+func formatThousands(n int64) string {
+	if n < 0 {
+		return "-" + formatThousands(-n)
+	}
+	if n < 1000 {
+		return fmt.Sprintf("%d", n)
+	}
+
+	return fmt.Sprintf("%v,%03d", formatThousands(n/1000), n%1000)
+}
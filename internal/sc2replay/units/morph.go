@@ -0,0 +1,22 @@
+package units
+
+// Ingame names of larvae, and of the eggs they turn into while morphing into
+// units.
+const Larva string = "Larva"
+const Egg string = "Egg"
+
+// Amount of units hatching from a single egg, by ingame name, if more than
+// one.
+var eggYield = map[string]int{
+	"Zergling": 2,
+}
+
+// Return the amount of units of the given ingame name hatching from a single
+// egg.
+func UnitsPerEgg(name string) int {
+	if yield, ok := eggYield[name]; ok {
+		return yield
+	}
+
+	return 1
+}
@@ -0,0 +1,27 @@
+package units
+
+// Supply provided by units and buildings, once completed.
+var SupplyProviders = map[string]float64{
+	// Protoss
+	"Nexus": 15,
+	"Pylon": 8,
+	// Terran
+	"CommandCenter":        15,
+	"CommandCenterFlying":  15,
+	"OrbitalCommand":       15,
+	"OrbitalCommandFlying": 15,
+	"PlanetaryFortress":    15,
+	"SupplyDepot":          8,
+	"SupplyDepotLowered":   8,
+	// Zerg
+	"Hatchery":          6,
+	"Lair":              6,
+	"Hive":              6,
+	"Overlord":          8,
+	"OverlordTransport": 8,
+	"Overseer":          8,
+	"OverseerSiegeMode": 8,
+}
+
+// Maximum supply a player can have.
+const SupplyLimit float64 = 200
@@ -0,0 +1,8 @@
+package units
+
+// Ingame names of worker units.
+var Workers = map[string]bool{
+	"Probe": true,
+	"SCV":   true,
+	"Drone": true,
+}
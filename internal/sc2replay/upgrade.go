@@ -0,0 +1,278 @@
+package sc2replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dragaera/probius/internal/sc2replay/events"
+	"github.com/icza/s2prot"
+)
+
+type UpgradeResearch struct {
+	// Human-readable names of the upgrade and of the building which
+	// researched it. Producer is empty if not known.
+	Name     string
+	Producer string
+
+	// Loops at which research started and finished. The replay's tracker
+	// events only tell when research finished, so Started is taken from
+	// the command which started it if it could be found in the game
+	// events.
+	Started  int64
+	Finished int64
+	// Whether Started is estimated from the research time in the unit
+	// catalog instead, as the command was not found. If research was sped
+	// up, eg by chronoboost, it actually started later than that.
+	Estimated bool
+}
+
+// Finds when players researched their upgrades, that is excluding the ones
+// players start with.
+type UpgradeResearches struct {
+	Replay *Replay
+
+	// Researched upgrades by player ID, in the order they finished.
+	Researches map[int64][]*UpgradeResearch
+
+	// Loop at which the first building of each type was finished, by
+	// player ID and ingame name. Research cannot start before that.
+	finishedBuildings map[int64]map[string]int64
+
+	// Commands of all players, and the index of the next one to consider.
+	commands    []playerCommand
+	nextCommand int
+	// Commands which might have started a research, by player ID
+	researchCommands map[int64][]*researchCommand
+	// Upgrade each ability was found to research, by ingame name.
+	abilityUpgrades map[events.CmdAbil]string
+}
+
+// Command using an ability without a target, while buildings which research
+// upgrades were selected.
+type researchCommand struct {
+	Loop int64
+	Abil events.CmdAbil
+	// Ingame names of the selected buildings
+	Buildings map[string]bool
+
+	// Whether it was found to start a research already
+	claimed bool
+}
+
+// Call this to find the upgrade researches.
+func (res *UpgradeResearches) Generate() error {
+	return Analyse(res.Replay, res)
+}
+
+func (res *UpgradeResearches) Start(sim *Simulator) error {
+	res.Researches = make(map[int64][]*UpgradeResearch)
+	res.finishedBuildings = make(map[int64]map[string]int64)
+	res.commands = res.Replay.playerCommands()
+	res.researchCommands = make(map[int64][]*researchCommand)
+	res.abilityUpgrades = make(map[events.CmdAbil]string)
+
+	return nil
+}
+
+func (res *UpgradeResearches) Observe(sim *Simulator, evt s2prot.Event) error {
+	// Selected buildings must be looked up in the state *before* the event.
+	res.collectCommands(sim, evt.Loop())
+
+	switch eventType := evt.EvtType.Name; eventType {
+	case "UnitBorn":
+		event := events.UnitBorn{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal UnitBorn event: %v", err)
+		}
+
+		res.buildingFinished(evt.Loop(), event.UpkeepPlayerID, event.UnitTypeName)
+	case "UnitDone":
+		event := events.UnitDone{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal UnitDone event: %v", err)
+		}
+
+		if unit, ok := sim.IngameUnits[unitTag(event.UnitTagIndex, event.UnitTagRecycle)]; ok {
+			res.buildingFinished(evt.Loop(), unit.OwnerID, unit.Name)
+		}
+	case "Upgrade":
+		event := events.Upgrade{}
+		if err := json.Unmarshal([]byte(evt.String()), &event); err != nil {
+			return fmt.Errorf("Unable to unmarshal Upgrade event: %v", err)
+		}
+
+		// Upgrades players start with, eg skins and sprays, are not
+		// researched.
+		if evt.Loop() == 0 {
+			return nil
+		}
+
+		if research, ok := res.newResearch(sim, evt, event.PlayerID, event.UpgradeTypeName); ok {
+			res.Researches[event.PlayerID] = append(res.Researches[event.PlayerID], research)
+		}
+	}
+
+	return nil
+}
+
+func (res *UpgradeResearches) Finish(sim *Simulator) error {
+	return nil
+}
+
+func (res *UpgradeResearches) buildingFinished(loop int64, playerID int64, name string) {
+	if _, ok := res.Replay.Catalog().Buildings[name]; !ok {
+		return
+	}
+
+	if _, ok := res.finishedBuildings[playerID]; !ok {
+		res.finishedBuildings[playerID] = make(map[string]int64)
+	}
+	if _, ok := res.finishedBuildings[playerID][name]; !ok {
+		res.finishedBuildings[playerID][name] = loop
+	}
+}
+
+func (res *UpgradeResearches) newResearch(sim *Simulator, evt s2prot.Event, playerID int64, name string) (*UpgradeResearch, bool) {
+	catalog := res.Replay.Catalog()
+	upgrade, ok := catalog.Upgrades[name]
+	if !ok {
+		return nil, false
+	}
+	loop := evt.Loop()
+
+	research := UpgradeResearch{
+		Name:      upgrade.Name,
+		Started:   loop,
+		Finished:  loop,
+		Estimated: true,
+	}
+	if producer, ok := catalog.Buildings[upgrade.Producer]; ok {
+		research.Producer = producer.Name
+	}
+
+	researchTicks, err := res.Replay.TicksUntilSeconds(upgrade.ResearchDuration().Seconds())
+	if err != nil {
+		sim.Diagnose(SeverityWarning, evt, fmt.Sprintf("Unable to determine start of research of %v: %v", upgrade.Name, err))
+		return &research, true
+	}
+
+	if command, ok := res.findResearchCommand(playerID, name, upgrade.Producer, loop, researchTicks); ok {
+		research.Started = command.Loop
+		research.Estimated = false
+		return &research, true
+	}
+
+	research.Started = loop - researchTicks
+
+	// Catalog research times might not match the game version, so we'd
+	// rather not claim research started before it could have.
+	if finished, ok := res.finishedBuildings[playerID][upgrade.Producer]; ok && research.Started < finished {
+		research.Started = finished
+	}
+	if research.Started < 0 {
+		research.Started = 0
+	}
+
+	return &research, true
+}
+
+// Remember the commands issued before the given loop which might have started
+// a research.
+func (res *UpgradeResearches) collectCommands(sim *Simulator, loop int64) {
+	catalog := res.Replay.Catalog()
+
+	for ; res.nextCommand < len(res.commands); res.nextCommand++ {
+		command := &res.commands[res.nextCommand]
+		if command.Loop >= loop {
+			return
+		}
+		if !command.untargeted() {
+			continue
+		}
+
+		buildings := make(map[string]bool)
+		for _, tag := range command.Selected {
+			unit, ok := sim.IngameUnits[tag]
+			if !ok || unit.OwnerID != command.PlayerID {
+				continue
+			}
+			if _, ok := catalog.Buildings[unit.Name]; ok {
+				buildings[unit.Name] = true
+			}
+		}
+		if len(buildings) == 0 {
+			continue
+		}
+
+		res.researchCommands[command.PlayerID] = append(res.researchCommands[command.PlayerID], &researchCommand{
+			Loop:      command.Loop,
+			Abil:      *command.Abil,
+			Buildings: buildings,
+		})
+	}
+}
+
+// Find the command which started researching the upgrade of the given ingame
+// name, which finished at the given loop. Research can only have been started
+// by a command issued while its producer was selected, around when research
+// would have had to start without being sped up. As catalog research times
+// might not match older game versions, commands up to a quarter of the
+// research time earlier are considered. As an ability always researches the
+// same upgrade, abilities found to research an upgrade before are preferred,
+// and abilities known to research other upgrades are ruled out. Of the
+// remaining commands, the one closest to the start without speedup is taken.
+func (res *UpgradeResearches) findResearchCommand(playerID int64, name string, producer string, finished int64, researchTicks int64) (*researchCommand, bool) {
+	expected := finished - researchTicks
+	earliest := expected - researchTicks/4
+	// Research can't have been sped up to take less than half its time.
+	latest := finished - researchTicks/2
+
+	var best *researchCommand
+	bestKnown := false
+	for _, command := range res.researchCommands[playerID] {
+		if command.claimed || command.Loop < earliest || command.Loop > latest {
+			continue
+		}
+		if !res.selectedProducer(command, producer) {
+			continue
+		}
+
+		upgrade, known := res.abilityUpgrades[command.Abil]
+		if known && upgrade != name {
+			continue
+		}
+
+		switch {
+		case best == nil, known && !bestKnown:
+			best, bestKnown = command, known
+		case known == bestKnown && abs(command.Loop-expected) < abs(best.Loop-expected):
+			best = command
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+
+	best.claimed = true
+	res.abilityUpgrades[best.Abil] = name
+
+	return best, true
+}
+
+// Whether the producer, or a building morphed from it such as a lair from a
+// hatchery, was selected.
+func (res *UpgradeResearches) selectedProducer(command *researchCommand, producer string) bool {
+	catalog := res.Replay.Catalog()
+
+	for name := range command.Buildings {
+		// Morph chains are short, but make sure to not loop forever
+		// should the catalog contain a cycle.
+		for i := 0; i < 5 && len(name) > 0; i++ {
+			if name == producer {
+				return true
+			}
+			name = catalog.Buildings[name].Producer
+		}
+	}
+
+	return false
+}
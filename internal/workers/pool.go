@@ -32,7 +32,7 @@ type Pool struct {
 func (pool *Pool) Run() error {
 	err := pool.Session.Open()
 	if err != nil {
-		return fmt.Errorf("Error connecting to Discord:", err)
+		return fmt.Errorf("Error connecting to Discord: %v", err)
 	}
 	defer pool.Session.Close()
 	log.Print("Discord connection established")
@@ -66,7 +66,7 @@ func Create(pool *Pool) (*Pool, error) {
 
 	dg, err := discordgo.New("Bot " + pool.Config.Discord.Token)
 	if err != nil {
-		return pool, fmt.Errorf("Error creating Discord session:", err)
+		return pool, fmt.Errorf("Error creating Discord session: %v", err)
 	}
 	pool.Session = dg
 
@@ -76,7 +76,7 @@ func Create(pool *Pool) (*Pool, error) {
 		0,  // DB
 	)
 	if err != nil {
-		return pool, fmt.Errorf("Error creating rate limiting Redis store: ", err)
+		return pool, fmt.Errorf("Error creating rate limiting Redis store: %v", err)
 	}
 
 	quota := throttled.RateQuota{
@@ -85,7 +85,7 @@ func Create(pool *Pool) (*Pool, error) {
 	}
 	rateLimiter, err := throttled.NewGCRARateLimiter(store, quota)
 	if err != nil {
-		return pool, fmt.Errorf("Error creating rate limiter: ", err)
+		return pool, fmt.Errorf("Error creating rate limiter: %v", err)
 	}
 	pool.rateLimiter = rateLimiter
 
@@ -200,7 +200,7 @@ func CheckStalePlayers(ctxt *JobContext, job *work.Job) error {
 	for _, user := range users {
 		err = user.LockForUpdate(ctxt.db)
 		if err != nil {
-			log.Printf("Error marking player for update: ", err)
+			log.Printf("Error marking player for update: %v", err)
 			return err
 		}
 		ctxt.enqueuer.Enqueue("check_last_replay", work.Q{"id": user.ID})